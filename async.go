@@ -0,0 +1,166 @@
+package glog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+AsyncConfig configures the asynchronous mode of a logger, see SetAsync.
+Above the high-water mark the queue sheds the entries below DropBelow
+so that a flood of chatter cannot stall the application, while the
+entries at or above DropBelow, and those without a level, wait for room
+so that the important lines are not lost.
+*/
+type AsyncConfig struct {
+	QueueSize int           // capacity of the queue, 1024 when zero
+	HighWater int           // queue length from which entries below DropBelow are dropped, 3/4 of QueueSize when zero
+	DropBelow int           // the zero value DEBUG drops nothing, WARNING drops DEBUG and INFO
+	MaxBlock  time.Duration // how long a kept entry waits for room in a full queue before being dropped, zero waits as long as needed
+}
+
+/*asyncItem is an entry waiting in the queue, or a marker whose done is closed once the entries before it are written.*/
+type asyncItem struct {
+	e    *Entry
+	done chan struct{}
+}
+
+/*asyncQueue hands the entries of a logger to its writer goroutine.*/
+type asyncQueue struct {
+	cfg     AsyncConfig
+	ring    *ring
+	closeMu sync.RWMutex // held for reading while pushing, for writing to close ring
+	closed  bool
+	stopped chan struct{} // closed when the writer goroutine is done
+	dropped uint64        // entries dropped so far, accessed atomically
+}
+
+/*
+SetAsync makes the logger hand its entries to a background goroutine which
+formats and writes them, so that logging calls do not wait for the output.
+A nil cfg writes all the queued entries and returns to synchronous writes.
+Fatal, Fatalf, Fatalln and FatalCode wait for the queue before exiting.
+Child loggers from With keep writing their lines synchronously through l.
+*/
+func (l *Logger) SetAsync(cfg *AsyncConfig) {
+	var q *asyncQueue
+	if cfg != nil {
+		q = newAsyncQueue(*cfg)
+		go q.run(l)
+	}
+	l.mu.Lock()
+	old := l.async
+	l.async = q
+	l.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+}
+
+func SetAsync(cfg *AsyncConfig) {
+	gStd.SetAsync(cfg)
+}
+
+func newAsyncQueue(cfg AsyncConfig) *asyncQueue {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.HighWater <= 0 || cfg.HighWater > cfg.QueueSize {
+		cfg.HighWater = cfg.QueueSize * 3 / 4
+	}
+	return &asyncQueue{cfg: cfg, ring: newRing(cfg.QueueSize), stopped: make(chan struct{})}
+}
+
+/*run writes the queued entries until the queue is closed.*/
+func (q *asyncQueue) run(l *Logger) {
+	defer close(q.stopped)
+	for {
+		item, ok := q.ring.pop()
+		if !ok {
+			return
+		}
+		if item.done != nil {
+			close(item.done)
+			continue
+		}
+		l.mu.Lock()
+		_ = l.writeEntry(item.e)
+		l.mu.Unlock()
+	}
+}
+
+/*
+push queues e, dropping it per the configuration when the queue is busy, or
+when cancel is closed before there is room for it. It reports whether e was
+dropped while waiting for room.
+*/
+func (q *asyncQueue) push(e *Entry, cancel <-chan struct{}) bool {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	if q.closed {
+		return false
+	}
+	item := asyncItem{e: e}
+	if e.Level != NOLEVEL && e.Level < q.cfg.DropBelow {
+		if q.ring.len() >= q.cfg.HighWater || !q.ring.tryPush(item) {
+			atomic.AddUint64(&q.dropped, 1)
+		}
+		return false
+	}
+	select {
+	case <-cancel:
+		atomic.AddUint64(&q.dropped, 1)
+		return true
+	default:
+	}
+	if q.ring.tryPush(item) {
+		return false
+	}
+	var timeout <-chan time.Time
+	if q.cfg.MaxBlock > 0 {
+		timer := time.NewTimer(q.cfg.MaxBlock)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	if q.ring.push(item, timeout, cancel) {
+		return false
+	}
+	atomic.AddUint64(&q.dropped, 1)
+	return true
+}
+
+/*flush waits until the entries queued before the call are written.*/
+func (q *asyncQueue) flush() {
+	q.closeMu.RLock()
+	if q.closed {
+		q.closeMu.RUnlock()
+		return
+	}
+	done := make(chan struct{})
+	q.ring.push(asyncItem{done: done}, nil, nil)
+	q.closeMu.RUnlock()
+	<-done
+}
+
+/*close writes the queued entries and stops the writer goroutine.*/
+func (q *asyncQueue) close() {
+	q.closeMu.Lock()
+	if !q.closed {
+		q.closed = true
+		q.ring.close()
+	}
+	q.closeMu.Unlock()
+	<-q.stopped
+}
+
+/*exit waits for the queued entries, then terminates the process with code.*/
+func (l *Logger) exit(code int) {
+	l.mu.Lock()
+	q := l.async
+	l.mu.Unlock()
+	if q != nil {
+		q.flush()
+	}
+	exit(code)
+}
@@ -0,0 +1,116 @@
+package glog
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/*gateWriter blocks every write until the gate is opened, signalling entered if set.*/
+type gateWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	if w.entered != nil {
+		w.entered <- struct{}{}
+	}
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncBackpressure(t *testing.T) {
+	w := &gateWriter{gate: make(chan struct{})}
+	logger := newEx(w, "", 0)
+	logger.SetAsync(&AsyncConfig{QueueSize: 8, HighWater: 4, DropBelow: WARNING})
+	defer logger.SetAsync(nil)
+
+	for i := 0; i < 100; i++ {
+		logger.Debug("chatter %d", i)
+		logger.Info("chatter %d", i)
+	}
+	done := make(chan struct{})
+	go func() {
+		// more than the queue holds, these have to wait for room
+		for i := 0; i < 20; i++ {
+			logger.Err("failure %d", i)
+		}
+		close(done)
+	}()
+	close(w.gate)
+	<-done
+	logger.async.flush()
+
+	out := w.String()
+	for i := 0; i < 20; i++ {
+		if !strings.Contains(out, "[ERROR]: failure "+strconv.Itoa(i)+"\n") {
+			t.Errorf("error line %d lost", i)
+		}
+	}
+	chatter := strings.Count(out, "chatter")
+	if chatter > 5 {
+		t.Errorf("%d chatter lines written above the high-water mark", chatter)
+	}
+	if dropped := logger.async.dropped; dropped != uint64(200-chatter) {
+		t.Errorf("dropped %d, want %d", dropped, 200-chatter)
+	}
+}
+
+func TestAsyncFatalWaits(t *testing.T) {
+	var code int
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	w := &gateWriter{gate: make(chan struct{})}
+	close(w.gate)
+	logger := newEx(w, "", 0)
+	logger.SetAsync(&AsyncConfig{})
+	defer logger.SetAsync(nil)
+	for i := 0; i < 100; i++ {
+		logger.Info("line %d", i)
+	}
+	logger.FatalCode(2, "bye")
+	if code != 2 || strings.Count(w.String(), "\n") != 101 || !strings.HasSuffix(w.String(), "[FATAL]: bye\n") {
+		t.Errorf("exit %d with %q", code, w.String())
+	}
+}
+
+func TestBarrier(t *testing.T) {
+	w := &gateWriter{gate: make(chan struct{})}
+	close(w.gate)
+	logger := newEx(w, "", 0)
+	logger.SetAsync(&AsyncConfig{QueueSize: 16})
+	defer logger.SetAsync(nil)
+	for round := 1; round <= 3; round++ {
+		for i := 0; i < 50; i++ {
+			logger.Info("line %d", i)
+		}
+		if err := logger.Barrier(); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(w.String(), "\n"); n != 50*round {
+			t.Fatalf("round %d: %d lines written at the barrier, want %d", round, n, 50*round)
+		}
+	}
+
+	h := &flushHandler{}
+	logger.SetHandler(h)
+	logger.Info("batched")
+	if err := logger.Barrier(); err != nil || h.entries != 1 || h.flushes != 1 {
+		t.Errorf("Barrier %v with %d entries and %d flushes, want 1 and 1", err, h.entries, h.flushes)
+	}
+}
@@ -0,0 +1,133 @@
+package glog
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+/*
+A BufferedWriter is an output gathering the lines of its loggers in memory
+and writing them to the underlying writer in one go, every flush interval
+and, once SetFlushBytes set a threshold, as soon as that many bytes are
+buffered, so that a burst reaches the disk promptly without waiting for the
+timer. Barrier and OnShutdown of the loggers writing to it flush it. An error
+of a flush from the timer is returned by the next Write or Flush. It must be
+closed to stop its timer, after the loggers writing to it. A BufferedWriter
+is safe for concurrent use.
+*/
+type BufferedWriter struct {
+	mu         sync.Mutex
+	w          io.Writer
+	buf        []byte
+	flushBytes int   // flush once so many bytes are buffered, 0 waits for the timer
+	err        error // of the last flush from the timer
+	stop       chan struct{}
+	stopped    chan struct{}
+	closed     bool
+}
+
+/*
+NewBufferedWriter returns a BufferedWriter writing to w every interval, an
+interval of zero or less flushing on the threshold and on demand only.
+*/
+func NewBufferedWriter(w io.Writer, interval time.Duration) *BufferedWriter {
+	b := &BufferedWriter{w: w, stop: make(chan struct{}), stopped: make(chan struct{})}
+	if interval > 0 {
+		go b.run(interval)
+	} else {
+		close(b.stopped)
+	}
+	return b
+}
+
+/*NewLogger creates a Logger writing to the buffer, with the rotation disabled.*/
+func (b *BufferedWriter) NewLogger(prefix string, flag int) *Logger {
+	l := newEx(b, prefix, flag)
+	l.splitFileSize = 0
+	return l
+}
+
+/*SetFlushBytes flushes the buffer as soon as it holds n bytes or more, 0 waits for the timer again.*/
+func (b *BufferedWriter) SetFlushBytes(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushBytes = n
+}
+
+/*Write buffers p, flushing the buffer if it reached the threshold of SetFlushBytes.*/
+func (b *BufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+	err := b.err
+	b.err = nil
+	b.buf = append(b.buf, p...)
+	if b.flushBytes > 0 && len(b.buf) >= b.flushBytes {
+		if ferr := b.flushLocked(); err == nil {
+			err = ferr
+		}
+	}
+	return len(p), err
+}
+
+/*Flush writes the buffered bytes to the underlying writer.*/
+func (b *BufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err := b.err
+	b.err = nil
+	if ferr := b.flushLocked(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+/*flushLocked writes the buffer out, the bytes of a failed write are dropped, b.mu must be held.*/
+func (b *BufferedWriter) flushLocked() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+/*run flushes the buffer every interval until Close.*/
+func (b *BufferedWriter) run(interval time.Duration) {
+	defer close(b.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.stop:
+			return
+		}
+		b.mu.Lock()
+		if err := b.flushLocked(); err != nil {
+			b.err = err
+		}
+		b.mu.Unlock()
+	}
+}
+
+/*Close stops the timer and flushes the buffer, the underlying writer is left open.*/
+func (b *BufferedWriter) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	select {
+	case <-b.stopped:
+	default:
+		close(b.stop)
+		<-b.stopped
+	}
+	return b.Flush()
+}
@@ -0,0 +1,66 @@
+package glog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*lockedBuffer is a bytes.Buffer safe for concurrent use.*/
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestBufferedWriterFlushBytes(t *testing.T) {
+	var out lockedBuffer
+	b := NewBufferedWriter(&out, time.Hour)
+	defer b.Close()
+	b.SetFlushBytes(100)
+	logger := b.NewLogger("", 0)
+	logger.Info("short")
+	if out.String() != "" {
+		t.Fatalf("flushed below the threshold: %q", out.String())
+	}
+	for i := 0; i < 10; i++ {
+		logger.Info("burst line %d", i)
+	}
+	if n := strings.Count(out.String(), "\n"); n < 7 {
+		t.Errorf("%d lines flushed by the burst, the timer is an hour away", n)
+	}
+	logger.Barrier()
+	if n := strings.Count(out.String(), "\n"); n != 11 {
+		t.Errorf("%d lines after Barrier, want 11", n)
+	}
+}
+
+func TestBufferedWriterInterval(t *testing.T) {
+	var out lockedBuffer
+	b := NewBufferedWriter(&out, 5*time.Millisecond)
+	logger := b.NewLogger("", 0)
+	logger.Info("waits for the timer")
+	for deadline := time.Now().Add(5 * time.Second); out.String() == ""; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the timer did not flush")
+		}
+	}
+	logger.Info("flushed by Close")
+	b.Close()
+	if want := "[INFO]: waits for the timer\n[INFO]: flushed by Close\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,244 @@
+package glog
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+/*
+LoggerConfig holds the tunables of a Logger as plain values, so that it can
+be stored as JSON and applied again, see Logger.Config and Logger.Apply. The
+flags and the levels are given by name, as ParseFlags and the level tokens
+spell them, and "" is NOLEVEL for PrintLevel and INFO for HeartbeatLevel. The
+settings holding functions or values, such as the filter, the handler, the
+line callbacks and suffix, the panic value, the archive handler, the output
+and the overflow of the rate limit, are left out, as is the async mode; Apply
+keeps the overflow.
+*/
+type LoggerConfig struct {
+	Flags           string        `json:"flags"`
+	Prefix          string        `json:"prefix"`
+	Level           string        `json:"level"`
+	PrintLevel      string        `json:"print_level"`
+	SplitBytes      uint64        `json:"split_bytes"` // 0 disables the rotation
+	SplitCount      int           `json:"split_count"`
+	LineRotate      int           `json:"line_rotate"`
+	ArchiveDir      string        `json:"archive_dir"`
+	ArchiveDirGrace time.Duration `json:"archive_dir_grace"` // see SetRemoveEmptyArchiveDir, 0 keeps the directory
+	FollowSymlink   bool          `json:"follow_symlink"`
+	RenameRetries   int           `json:"rename_retries"`
+	RenameDelay     time.Duration `json:"rename_delay"`
+	CopyTruncate    bool          `json:"copy_truncate"`
+	Formatter       string        `json:"formatter"` // "text", "json", "logfmt" or "custom" for another Formatter
+	HeaderSeparator string        `json:"header_separator"`
+	HeaderFrames    int           `json:"header_frames"`
+	HeaderPerLine   bool          `json:"header_per_line"`
+	LevelPadding    int           `json:"level_padding"`
+	CompactLevel    bool          `json:"compact_level"`
+	Bare            bool          `json:"bare"`
+	HeartbeatLevel  string        `json:"heartbeat_level"`
+	Location        string        `json:"location"` // name of the zone as for time.LoadLocation, "" is the local one
+	TimeResolution  time.Duration `json:"time_resolution"`
+	OrderedTime     bool          `json:"ordered_timestamps"`
+	AutoColor       bool          `json:"auto_color"`
+	SkipEmpty       bool          `json:"skip_empty"`
+	ReplaceUTF8     bool          `json:"replace_invalid_utf8"`
+	MaxFields       int           `json:"max_fields"`
+	MaxMessageSize  int           `json:"max_message_size"`
+	TruncSummary    bool          `json:"truncation_summary"`
+	NilRender       *string       `json:"nil_render,omitempty"` // nil keeps the default rendering
+	LevelSampling   map[int]int   `json:"level_sampling,omitempty"`
+	Burst           int           `json:"burst"`
+	BurstThereafter int           `json:"burst_thereafter"`
+	RateLimit       int           `json:"rate_limit"` // entries per second, 0 for no limit
+	ErrorCooldown   time.Duration `json:"error_cooldown"`
+}
+
+/*Config returns the tunables of the logger, see LoggerConfig.*/
+func (l *Logger) Config() LoggerConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := LoggerConfig{
+		Flags:           FormatFlags(l.flag),
+		Prefix:          l.prefix,
+		Level:           levelName(int(atomic.LoadInt32(&l.level))),
+		SplitBytes:      l.splitFileSize,
+		SplitCount:      l.totalRotateSplit,
+		LineRotate:      l.lineRotate,
+		ArchiveDir:      l.archiveDir,
+		ArchiveDirGrace: l.archiveGrace,
+		FollowSymlink:   l.followSymlink,
+		RenameRetries:   l.renameRetries,
+		RenameDelay:     l.renameDelay,
+		CopyTruncate:    l.copyTruncate,
+		Formatter:       formatterName(l.formatter),
+		HeaderSeparator: l.headerSep,
+		HeaderFrames:    l.headerFrames,
+		HeaderPerLine:   l.headerPerLine,
+		LevelPadding:    l.levelPad,
+		CompactLevel:    l.compactLevel,
+		Bare:            l.bare,
+		HeartbeatLevel:  levelName(INFO + l.beatLevel),
+		TimeResolution:  l.resolution,
+		OrderedTime:     l.orderedTime,
+		AutoColor:       l.autoColor,
+		SkipEmpty:       l.skipEmpty,
+		ReplaceUTF8:     l.replaceUTF8,
+		MaxFields:       l.maxFields,
+		MaxMessageSize:  l.maxMessage,
+		TruncSummary:    l.truncSummary,
+	}
+	if level := l.printLevelOf(); level != NOLEVEL {
+		c.PrintLevel = levelName(level)
+	}
+	if l.location != nil {
+		c.Location = l.location.String()
+	}
+	if l.renderNil {
+		s := l.nilRender
+		c.NilRender = &s
+	}
+	if s, _ := l.sampling.Load().(*levelSampling); s != nil {
+		c.LevelSampling = make(map[int]int)
+		for level, every := range s.every {
+			if every > 1 {
+				c.LevelSampling[level] = int(every)
+			}
+		}
+	}
+	if l.burst != nil {
+		c.Burst, c.BurstThereafter = int(l.burst.burst), int(l.burst.thereafter)
+	}
+	if l.rate != nil {
+		c.RateLimit = int(l.rate.rate)
+	}
+	if cooldown, _ := l.cooldown.Load().(*errorCooldown); cooldown != nil {
+		c.ErrorCooldown = cooldown.d
+	}
+	return c
+}
+
+func Config() LoggerConfig {
+	return gStd.Config()
+}
+
+/*
+Apply sets the tunables of the logger to those of c, e.g. as returned by
+Config earlier. The bytes already written to the active file count against
+the new split size. A Formatter is only replaced when c names another kind of
+it, so a "custom" one can only be kept. Nothing is changed when c is invalid.
+*/
+func (l *Logger) Apply(c LoggerConfig) error {
+	flag, err := ParseFlags(c.Flags)
+	if err != nil {
+		return err
+	}
+	level, ok := parseLevel(c.Level)
+	if !ok {
+		return fmt.Errorf("glog: unknown level %q", c.Level)
+	}
+	printLevel := NOLEVEL
+	if c.PrintLevel != "" {
+		if printLevel, ok = parseLevel(c.PrintLevel); !ok {
+			return fmt.Errorf("glog: unknown print level %q", c.PrintLevel)
+		}
+	}
+	beatLevel := INFO
+	if c.HeartbeatLevel != "" {
+		if beatLevel, ok = parseLevel(c.HeartbeatLevel); !ok {
+			return fmt.Errorf("glog: unknown heartbeat level %q", c.HeartbeatLevel)
+		}
+	}
+	var loc *time.Location
+	if c.Location != "" {
+		if loc, err = time.LoadLocation(c.Location); err != nil {
+			return err
+		}
+	}
+	l.mu.Lock()
+	current := formatterName(l.formatter)
+	l.mu.Unlock()
+	var formatter Formatter
+	switch {
+	case c.Formatter == current:
+	case c.Formatter == "text":
+	case c.Formatter == "json":
+		formatter = &JSONFormatter{}
+	case c.Formatter == "logfmt":
+		formatter = &LogfmtFormatter{}
+	default:
+		return fmt.Errorf("glog: cannot apply the %q formatter over a %q one", c.Formatter, current)
+	}
+	if err := l.SetArchiveDir(c.ArchiveDir); err != nil {
+		return err
+	}
+	l.SetRemoveEmptyArchiveDir(c.ArchiveDirGrace)
+	l.SetRenameRetry(c.RenameRetries, c.RenameDelay)
+	l.SetCopyTruncate(c.CopyTruncate)
+	l.SetLevelSampling(c.LevelSampling)
+	l.SetBurstSampler(c.Burst, c.BurstThereafter)
+	l.mu.Lock()
+	var overflow io.Writer
+	if l.rate != nil {
+		overflow = l.rate.overflow
+	}
+	l.mu.Unlock()
+	l.SetRateLimit(c.RateLimit, overflow)
+	l.SetErrorCooldown(c.ErrorCooldown)
+	atomic.StoreInt32(&l.level, int32(level))
+	l.SetPrintLevel(printLevel)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flag = flag
+	l.prefix = c.Prefix
+	l.splitFileSize = c.SplitBytes
+	l.totalRotateSplit = c.SplitCount
+	l.lineRotate = c.LineRotate
+	l.followSymlink = c.FollowSymlink
+	if c.Formatter != current {
+		l.formatter = formatter
+	}
+	l.headerSep = c.HeaderSeparator
+	l.headerFrames = c.HeaderFrames
+	l.headerPerLine = c.HeaderPerLine
+	l.levelPad = c.LevelPadding
+	l.compactLevel = c.CompactLevel
+	l.bare = c.Bare
+	l.beatLevel = beatLevel - INFO
+	l.location = loc
+	l.resolution = c.TimeResolution
+	l.orderedTime = c.OrderedTime
+	l.autoColor = c.AutoColor
+	l.color = c.AutoColor && isTerminal(l.out)
+	l.skipEmpty = c.SkipEmpty
+	l.replaceUTF8 = c.ReplaceUTF8
+	l.maxFields = c.MaxFields
+	l.maxMessage = c.MaxMessageSize
+	l.truncSummary = c.TruncSummary
+	l.renderNil = c.NilRender != nil
+	l.nilRender = ""
+	if c.NilRender != nil {
+		l.nilRender = *c.NilRender
+	}
+	return nil
+}
+
+func Apply(c LoggerConfig) error {
+	return gStd.Apply(c)
+}
+
+/*formatterName names the kind of f for LoggerConfig.*/
+func formatterName(f Formatter) string {
+	switch f.(type) {
+	case nil:
+		return "text"
+	case *JSONFormatter:
+		return "json"
+	case *LogfmtFormatter:
+		return "logfmt"
+	}
+	return "custom"
+}
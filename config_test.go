@@ -0,0 +1,79 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[app] ", LstdFlags|Lshortfile)
+	logger.SetLevel(INFO)
+	logger.SetPrintLevel(WARNING)
+	logger.SetLineRotate(100)
+	logger.SetLocation(time.UTC)
+	logger.SetTimeResolution(time.Second)
+	logger.SetNilRender("null")
+	logger.SetLevelSampling(map[int]int{DEBUG: 5})
+	logger.SetBurstSampler(10, 100)
+	logger.SetFormatter(&JSONFormatter{Indent: "  "})
+	if err := logger.SetArchiveDir(filepath.Join(t.TempDir(), "old")); err != nil {
+		t.Fatal(err)
+	}
+	saved := logger.Config()
+
+	// persist it as JSON
+	data, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded LoggerConfig
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Fatalf("JSON round trip changed the config:\n%+v\n%+v", loaded, saved)
+	}
+
+	logger.Reset()
+	if reflect.DeepEqual(logger.Config(), saved) {
+		t.Fatal("Reset kept the config")
+	}
+	logger.SetFormatter(&JSONFormatter{Indent: "  "})
+	if err := logger.Apply(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got := logger.Config(); !reflect.DeepEqual(got, saved) {
+		t.Errorf("restored config\n%+v\nwant\n%+v", got, saved)
+	}
+	if f, _ := logger.formatter.(*JSONFormatter); f == nil || f.Indent != "  " {
+		t.Errorf("formatter of the same kind replaced by %#v", logger.formatter)
+	}
+
+	for _, bad := range []LoggerConfig{
+		{Flags: "bogus", Level: "INFO", Formatter: "text"},
+		{Level: "LOUD", Formatter: "text"},
+		{Level: "INFO", Formatter: "text", Location: "Nowhere/Void"},
+		{Level: "INFO", Formatter: "custom"},
+	} {
+		if err := logger.Apply(bad); err == nil {
+			t.Errorf("Apply(%+v) succeeded", bad)
+		}
+	}
+	if got := logger.Config(); !reflect.DeepEqual(got, saved) {
+		t.Errorf("invalid configs changed the logger to %+v", got)
+	}
+
+	// a hand-written config may leave the optional levels out
+	logger.SetHeartbeatLevel(WARNING)
+	if err := logger.Apply(LoggerConfig{Level: "INFO", Formatter: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	if c := logger.Config(); c.PrintLevel != "" || c.HeartbeatLevel != "INFO" {
+		t.Errorf("print level %q, heartbeat level %q, want \"\" and INFO", c.PrintLevel, c.HeartbeatLevel)
+	}
+}
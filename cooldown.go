@@ -0,0 +1,85 @@
+package glog
+
+import (
+	"sync"
+	"time"
+)
+
+/*cooldownKeys bounds the number of categories an error cooldown tracks at once.*/
+const cooldownKeys = 1024
+
+/*errorCooldown holds the categories of errors in their cooldown, see SetErrorCooldown.*/
+type errorCooldown struct {
+	d          time.Duration
+	mu         sync.Mutex
+	categories map[string]*cooldownMark
+}
+
+/*cooldownMark is the cooldown of a category.*/
+type cooldownMark struct {
+	until      time.Time
+	suppressed int
+}
+
+/*
+SetErrorCooldown quiets the cascades of errors: once an error of a category
+is written, the errors of the same category are dropped for d, after which
+the next one is written again, carrying the number of errors dropped in the
+meantime as the field suppressed, and starts another cooldown. The category
+of Err and WrapErr is their format string, so that the errors differing by
+their arguments only count as one; that of ErrKV is the value of its key
+"category", or else its message. The other levels and the errors logged
+otherwise are not affected. Up to 1024 categories are tracked at once: when
+more come, the cooldowns start over. A d of 0 or less turns the cooldown off.
+Child loggers from With share the cooldown of l at the time of the call.
+*/
+func (l *Logger) SetErrorCooldown(d time.Duration) {
+	var c *errorCooldown
+	if d > 0 {
+		c = &errorCooldown{d: d, categories: make(map[string]*cooldownMark)}
+	}
+	l.cooldown.Store(c)
+}
+
+func SetErrorCooldown(d time.Duration) {
+	gStd.SetErrorCooldown(d)
+}
+
+/*
+coolDown reports whether the next error of category is written and returns
+fields with the count of the errors dropped before it, if any.
+*/
+func (l *Logger) coolDown(category string, fields []Field) ([]Field, bool) {
+	c, _ := l.cooldown.Load().(*errorCooldown)
+	if c == nil {
+		return fields, true
+	}
+	t := now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mark, ok := c.categories[category]
+	if !ok {
+		if len(c.categories) >= cooldownKeys {
+			c.categories = make(map[string]*cooldownMark)
+		}
+		c.categories[category] = &cooldownMark{until: t.Add(c.d)}
+		return fields, true
+	}
+	if t.Before(mark.until) {
+		mark.suppressed++
+		return fields, false
+	}
+	if mark.suppressed > 0 {
+		fields = append(fields, Field{Key: "suppressed", Value: mark.suppressed})
+	}
+	mark.until, mark.suppressed = t.Add(c.d), 0
+	return fields, true
+}
+
+/*kvCategory returns the category of an ErrKV call, see SetErrorCooldown.*/
+func kvCategory(msg string, kv map[string]interface{}) string {
+	if category, ok := kv["category"].(string); ok {
+		return category
+	}
+	return msg
+}
@@ -0,0 +1,45 @@
+package glog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetErrorCooldown(t *testing.T) {
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetErrorCooldown(time.Minute)
+	for i := 0; i < 5; i++ {
+		logger.Err("query %d failed", i)
+		logger.ErrKV("timeout", map[string]interface{}{"category": "db", "attempt": i})
+		logger.WrapErr(errors.New("refused"), "dial %s", "db1")
+		logger.Info("retrying")
+	}
+	want := "[ERROR]: query 0 failed\n" + "[ERROR]: timeout attempt=0 category=db\n" + "[ERROR]: dial db1: refused\n" +
+		"[INFO]: retrying\n[INFO]: retrying\n[INFO]: retrying\n[INFO]: retrying\n[INFO]: retrying\n"
+	if buf.String() != want {
+		t.Errorf("within the cooldown: got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	clock = clock.Add(time.Minute)
+	logger.Err("query %d failed", 5)
+	logger.Err("query %d failed", 6)
+	logger.ErrKV("timeout", map[string]interface{}{"category": "db"})
+	if want := "[ERROR]: query 5 failed suppressed=4\n[ERROR]: timeout category=db suppressed=4\n"; buf.String() != want {
+		t.Errorf("after the cooldown: got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	logger.SetErrorCooldown(0)
+	logger.Err("query %d failed", 7)
+	logger.Err("query %d failed", 8)
+	if want := "[ERROR]: query 7 failed\n[ERROR]: query 8 failed\n"; buf.String() != want {
+		t.Errorf("without cooldown: got %q, want %q", buf.String(), want)
+	}
+}
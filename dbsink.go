@@ -0,0 +1,127 @@
+package glog
+
+import (
+	"database/sql"
+	"io"
+	"strconv"
+	"sync"
+)
+
+/*DB_BATCH_SIZE is the default number of entries a DBSink inserts at once.*/
+const DB_BATCH_SIZE = 64
+
+/*
+A DBSink is a Handler storing the entries as rows of a database table, e.g.
+a local SQLite file for searchable logs. It inserts the rows in batches,
+within one transaction per batch, so the entries reach the table once
+BatchSize of them are pending, an entry at FATAL arrives, or Flush or Close
+is called. The table needs the columns
+
+	time, level, file, message, fields
+
+receiving the time of the entry, the level name, "file:line" of the caller,
+the message and the entry fields as a JSON object. The statement uses "?"
+placeholders, as SQLite and MySQL do. A DBSink is safe for concurrent use.
+*/
+type DBSink struct {
+	BatchSize int // entries per insert batch, DB_BATCH_SIZE when zero
+
+	mu      sync.Mutex
+	db      *sql.DB
+	insert  string
+	pending []Entry
+}
+
+/*NewDBSink returns a DBSink inserting into table of db, table is used in the statement as is.*/
+func NewDBSink(db *sql.DB, table string) *DBSink {
+	return &DBSink{BatchSize: DB_BATCH_SIZE, db: db,
+		insert: "INSERT INTO " + table + " (time, level, file, message, fields) VALUES (?, ?, ?, ?, ?)"}
+}
+
+/*NewLogger creates a Logger handing its entries to the sink, with the rotation disabled.*/
+func (s *DBSink) NewLogger(prefix string, flag int) *Logger {
+	l := newEx(io.Discard, prefix, flag)
+	l.splitFileSize = 0
+	l.handler = s
+	return l
+}
+
+/*Handle implements Handler.*/
+func (s *DBSink) Handle(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := *e
+	entry.Fields = append([]Field(nil), e.Fields...)
+	s.pending = append(s.pending, entry)
+	batch := s.BatchSize
+	if batch <= 0 {
+		batch = DB_BATCH_SIZE
+	}
+	if len(s.pending) >= batch || e.Level == FATAL {
+		return s.flush()
+	}
+	return nil
+}
+
+/*Flush inserts the pending entries.*/
+func (s *DBSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+/*Close inserts the pending entries, the database is left open.*/
+func (s *DBSink) Close() error {
+	return s.Flush()
+}
+
+/*flush inserts the pending entries in one transaction, s.mu must be held.*/
+func (s *DBSink) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(s.insert)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	var fields []byte
+	for i := range s.pending {
+		e := &s.pending[i]
+		file := ""
+		if e.File != "" {
+			file = e.File + ":" + strconv.Itoa(e.Line)
+		}
+		level := ""
+		if e.Level != NOLEVEL {
+			level = levelName(e.Level)
+		}
+		fields = fields[:0]
+		for _, field := range e.Fields {
+			fields = appendJSONField(fields, field)
+		}
+		if len(fields) > 0 {
+			fields[0] = '{' // the comma of the first field
+		} else {
+			fields = append(fields, '{')
+		}
+		fields = append(fields, '}')
+		if _, err := stmt.Exec(e.Time, level, file, trimNewline(e.Message), string(fields)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for i := range s.pending {
+		s.pending[i] = Entry{}
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
@@ -0,0 +1,103 @@
+package glog
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*fakeDB is a database/sql driver storing the inserted rows in memory.*/
+type fakeDB struct {
+	mu      sync.Mutex
+	query   string
+	rows    [][]driver.Value
+	commits int
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.query = query
+	return fakeStmt{c.d}, nil
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{c.d}, nil }
+
+type fakeStmt struct{ d *fakeDB }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return 5 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.rows = append(s.d.rows, args)
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) { return nil, errors.New("not supported") }
+
+type fakeTx struct{ d *fakeDB }
+
+func (t fakeTx) Commit() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	t.d.commits++
+	return nil
+}
+func (t fakeTx) Rollback() error { return nil }
+
+var fakeDriver = &fakeDB{}
+
+func init() { sql.Register("glogfake", fakeDriver) }
+
+func TestDBSink(t *testing.T) {
+	db, err := sql.Open("glogfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sink := NewDBSink(db, "logs")
+	sink.BatchSize = 2
+	logger := sink.NewLogger("", Lshortfile)
+	logger.With("user", "yax").Info("first")
+	if len(fakeDriver.rows) != 0 {
+		t.Fatalf("inserted before the batch was full")
+	}
+	logger.Err("second\n")
+	logger.Println("third")
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fakeDriver.query != "INSERT INTO logs (time, level, file, message, fields) VALUES (?, ?, ?, ?, ?)" {
+		t.Errorf("query %q", fakeDriver.query)
+	}
+	if len(fakeDriver.rows) != 3 || fakeDriver.commits != 2 {
+		t.Fatalf("%d rows in %d commits, want 3 in 2", len(fakeDriver.rows), fakeDriver.commits)
+	}
+	row := fakeDriver.rows[0]
+	if _, ok := row[0].(time.Time); !ok || row[1] != "INFO" || row[3] != "first" {
+		t.Errorf("unexpected row %v", row)
+	}
+	if file, _ := row[2].(string); !strings.Contains(file, "dbsink_test.go:") {
+		t.Errorf("file column %q", file)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(row[4].(string)), &fields); err != nil || fields["user"] != "yax" {
+		t.Errorf("fields column %q: %v", row[4], err)
+	}
+	if row := fakeDriver.rows[1]; row[1] != "ERROR" || row[3] != "second" || row[4] != "{}" {
+		t.Errorf("unexpected row %v", row)
+	}
+	if row := fakeDriver.rows[2]; row[1] != "" || row[3] != "third" {
+		t.Errorf("unexpected row %v", row)
+	}
+}
@@ -0,0 +1,50 @@
+package glog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+)
+
+/*entryIDBytes is the number of random bytes of an identifier of Lentryid, written as twice as many hex digits.*/
+const entryIDBytes = 6
+
+/*fallbackID numbers the identifiers when crypto/rand fails, which should not happen.*/
+var fallbackID uint64
+
+/*randomEntryID returns 12 random lower case hex digits, e.g. 3f9a0c12d4e7.*/
+func randomEntryID() string {
+	var b [entryIDBytes]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		n := atomic.AddUint64(&fallbackID, 1)
+		for i := range b {
+			b[len(b)-1-i] = byte(n >> (8 * i))
+		}
+	}
+	return hex.EncodeToString(b[:])
+}
+
+/*entryID returns the identifier of the next entry, l.mu must be held.*/
+func (l *Logger) entryID() string {
+	if l.entryIDFunc != nil {
+		return l.entryIDFunc()
+	}
+	return randomEntryID()
+}
+
+/*
+SetEntryIDFunc sets the function generating the identifiers written with
+Lentryid, e.g. a ULID generator, or a counter for deterministic tests. By
+default they are 12 random hex digits, enough to tell apart the lines of a
+burst when correlating them across outputs. It is called with the logger
+locked, so it must be cheap and must not log. A nil f restores the default.
+*/
+func (l *Logger) SetEntryIDFunc(f func() string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entryIDFunc = f
+}
+
+func SetEntryIDFunc(f func() string) {
+	gStd.SetEntryIDFunc(f)
+}
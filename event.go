@@ -0,0 +1,93 @@
+package glog
+
+import "sync"
+
+/*
+An Event builds a structured entry field by field without going through
+fmt: Log starts it, the typed methods add fields and Msg writes it.
+
+	logger.Log(glog.INFO).Str("user", "yax").Int("n", 5).Msg("done")
+
+Log returns a nil Event, on which all methods are no-ops, when the level is
+filtered out. Events are pooled, an Event must not be used once Msg returned.
+*/
+type Event struct {
+	l      *Logger
+	level  int
+	fields []Field
+}
+
+var eventPool = sync.Pool{New: func() interface{} { return &Event{fields: make([]Field, 0, 8)} }}
+
+/*Log starts an Event of the given level.*/
+func (l *Logger) Log(level int) *Event {
+	if !l.enabled(level) {
+		return nil
+	}
+	e := eventPool.Get().(*Event)
+	e.l = l
+	e.level = level
+	return e
+}
+func Log(level int) *Event {
+	return gStd.Log(level)
+}
+
+/*Str adds a string field.*/
+func (e *Event) Str(key, value string) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Value: value})
+	return e
+}
+
+/*Int adds an int field.*/
+func (e *Event) Int(key string, value int) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Value: value})
+	return e
+}
+
+/*Bool adds a bool field.*/
+func (e *Event) Bool(key string, value bool) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Value: value})
+	return e
+}
+
+/*Err adds the error under the "error" key.*/
+func (e *Event) Err(err error) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: "error", Value: err})
+	return e
+}
+
+/*Any adds a field of any type, rendered with fmt unless it is one of the common types.*/
+func (e *Event) Any(key string, value interface{}) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Value: value})
+	return e
+}
+
+/*Msg writes the entry with msg as its message and releases the Event.*/
+func (e *Event) Msg(msg string) {
+	if e == nil {
+		return
+	}
+	e.l.output(2, e.level, e.fields, msg, nil)
+	for i := range e.fields {
+		e.fields[i] = Field{}
+	}
+	e.l = nil
+	e.fields = e.fields[:0]
+	eventPool.Put(e)
+}
@@ -0,0 +1,47 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestEvent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[Info] ", 0)
+	logger.Log(INFO).Str("k", "v").Int("n", 5).Bool("ok", true).Err(errors.New("eof")).Any("f", 1.5).Msg("done")
+	logger.Log(DEBUG).Msg("bare\n")
+	want := "[Info] [INFO]: done k=v n=5 ok=true error=eof f=1.5\n[Info] [DEBUG]: bare\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	logger.SetFormatter(&JSONFormatter{})
+	logger.Log(WARNING).Str("k", "v").Int("n", 5).Msg("done")
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "done" || record["k"] != "v" || record["n"] != 5.0 {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func BenchmarkEvent(b *testing.B) {
+	logger := newEx(io.Discard, "[Info] ", LstdFlags)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Log(INFO).Str("user", "yax").Int("n", 5).Msg("done")
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	logger := newEx(io.Discard, "[Info] ", LstdFlags)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("done user=%s n=%d", "yax", 5)
+	}
+}
@@ -0,0 +1,263 @@
+package glog
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*A Field is a single key/value pair attached to log entries.*/
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+var (
+	globalMu     sync.RWMutex // protects globalFields
+	globalFields []Field      // fields applied to the entries of every logger
+)
+
+/*
+fieldsFromKeyvals turns an alternating key/value list into fields.
+Non-string keys are rendered with fmt.Sprint and a trailing key
+without a value gets the value "!MISSING".
+*/
+func fieldsFromKeyvals(keyvals []interface{}) []Field {
+	fields := make([]Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		var value interface{} = "!MISSING"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return fields
+}
+
+/*fieldsFromMap turns kv into fields sorted by key, so that the lines do not depend on the map order.*/
+func fieldsFromMap(kv map[string]interface{}) []Field {
+	fields := make([]Field, 0, len(kv))
+	for key, value := range kv {
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+/*
+SetGlobalFields sets the key/value pairs applied to every entry of every logger,
+replacing the previous set. The text format renders them right after the prefix
+as key=value pairs. Call it without arguments to clear them.
+*/
+func SetGlobalFields(keyvals ...interface{}) {
+	fields := fieldsFromKeyvals(keyvals)
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalFields = fields
+}
+
+/*GlobalFields returns a copy of the fields set by SetGlobalFields.*/
+func GlobalFields() []Field {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return append([]Field(nil), globalFields...)
+}
+
+/*
+With returns a child logger adding the given key/value pairs to each of its entries,
+after the fields of l. The child starts with a copy of the settings of l and
+writes its lines through l, so both share the output, the rotation and the line
+callbacks; a Handler set on l at the time of the call is shared as well.
+*/
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fields := append(l.fields[:len(l.fields):len(l.fields)], fieldsFromKeyvals(keyvals)...)
+	child := &Logger{settings: l.settings, out: loggerWriter{l}, totalRotateSplit: l.totalRotateSplit,
+		level: atomic.LoadInt32(&l.level), printLevel: atomic.LoadInt32(&l.printLevel)}
+	child.fields = fields
+	if child.seqOwner = l.seqOwner; child.seqOwner == nil {
+		child.seqOwner = l // the lines end up in the output of l, numbered along with its own
+	}
+	if s := l.sampling.Load(); s != nil {
+		child.sampling.Store(s)
+	}
+	if c := l.cooldown.Load(); c != nil {
+		child.cooldown.Store(c)
+	}
+	return child
+}
+func With(keyvals ...interface{}) *Logger {
+	return gStd.With(keyvals...)
+}
+
+/*loggerWriter hands the lines written by a child logger to its parent.*/
+type loggerWriter struct {
+	l *Logger
+}
+
+func (w loggerWriter) Write(p []byte) (int, error) {
+	w.l.mu.Lock()
+	defer w.l.mu.Unlock()
+	return w.l.emit(p)
+}
+
+/*appendGlobalFields writes the global fields to buf as "key=value " pairs.*/
+func appendGlobalFields(buf *[]byte) {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	for _, f := range globalFields {
+		*buf = append(*buf, f.Key...)
+		*buf = append(*buf, '=')
+		*buf = appendValue(*buf, f.Value)
+		*buf = append(*buf, ' ')
+	}
+}
+
+/*
+isNil reports whether v is nil or holds a nil pointer, map, slice, channel
+or function, e.g. an error returned as a nil *MyError.
+*/
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return rv.IsNil()
+	}
+	return false
+}
+
+/*replaceNil returns fields with their nil values replaced by s, copying fields only when one of them is nil.*/
+func replaceNil(fields []Field, s string) []Field {
+	copied := false
+	for i, f := range fields {
+		if !isNil(f.Value) {
+			continue
+		}
+		if !copied {
+			fields = append([]Field(nil), fields...)
+			copied = true
+		}
+		fields[i].Value = s
+	}
+	return fields
+}
+
+/*
+appendValue writes the text form of a field value to buf, sparing fmt for the common types.
+The errors render as their Error string, a nil error as <nil> like a nil value.
+*/
+func appendValue(buf []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(buf, v...)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case error:
+		if isNil(v) {
+			return append(buf, "<nil>"...)
+		}
+		return append(buf, v.Error()...)
+	case time.Duration:
+		return append(buf, v.String()...)
+	}
+	return append(buf, fmt.Sprint(v)...)
+}
+
+/*
+BuildInfoFields returns the "version" and "revision" key/value pairs of the running
+binary as read from debug.ReadBuildInfo, ready to be passed to SetGlobalFields:
+
+	glog.SetGlobalFields(glog.BuildInfoFields()...)
+
+The revision is only present when the binary was built with VCS stamping.
+*/
+func BuildInfoFields() []interface{} {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	version := info.Main.Version
+	if version == "" {
+		version = "(devel)"
+	}
+	keyvals := []interface{}{"version", version}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			keyvals = append(keyvals, "revision", s.Value)
+		}
+	}
+	return keyvals
+}
+
+/*
+DebugKV logs msg at DEBUG with the pairs of kv as fields sorted by key,
+rendered k1=v1 k2=v2 in the text format.
+*/
+func (l *Logger) DebugKV(msg string, kv map[string]interface{}) {
+	if l.enabled(DEBUG) {
+		l.output(2, DEBUG, fieldsFromMap(kv), msg, nil)
+	}
+}
+func DebugKV(msg string, kv map[string]interface{}) {
+	if gStd.enabled(DEBUG) {
+		gStd.output(2, DEBUG, fieldsFromMap(kv), msg, nil)
+	}
+}
+
+/*InfoKV logs msg at INFO with the pairs of kv as fields sorted by key.*/
+func (l *Logger) InfoKV(msg string, kv map[string]interface{}) {
+	if l.enabled(INFO) {
+		l.output(2, INFO, fieldsFromMap(kv), msg, nil)
+	}
+}
+func InfoKV(msg string, kv map[string]interface{}) {
+	if gStd.enabled(INFO) {
+		gStd.output(2, INFO, fieldsFromMap(kv), msg, nil)
+	}
+}
+
+/*WarnKV logs msg at WARNING with the pairs of kv as fields sorted by key.*/
+func (l *Logger) WarnKV(msg string, kv map[string]interface{}) {
+	if l.enabled(WARNING) {
+		l.output(2, WARNING, fieldsFromMap(kv), msg, nil)
+	}
+}
+func WarnKV(msg string, kv map[string]interface{}) {
+	if gStd.enabled(WARNING) {
+		gStd.output(2, WARNING, fieldsFromMap(kv), msg, nil)
+	}
+}
+
+/*ErrKV logs msg at ERROR with the pairs of kv as fields sorted by key.*/
+func (l *Logger) ErrKV(msg string, kv map[string]interface{}) {
+	if l.enabled(ERROR) {
+		if fields, ok := l.coolDown(kvCategory(msg, kv), fieldsFromMap(kv)); ok {
+			l.output(2, ERROR, fields, msg, nil)
+		}
+	}
+}
+func ErrKV(msg string, kv map[string]interface{}) {
+	if gStd.enabled(ERROR) {
+		if fields, ok := gStd.coolDown(kvCategory(msg, kv), fieldsFromMap(kv)); ok {
+			gStd.output(2, ERROR, fields, msg, nil)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package glog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGlobalFields(t *testing.T) {
+	defer SetGlobalFields()
+	keyvals := BuildInfoFields()
+	if len(keyvals) < 2 || keyvals[0] != "version" {
+		t.Fatalf("BuildInfoFields() = %v, want a version field", keyvals)
+	}
+	SetGlobalFields(keyvals...)
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[Info] ", 0)
+	logger.Println("Hello!")
+	want := "[Info] version=" + keyvals[1].(string) + " "
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("got %q, want prefix %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	SetGlobalFields()
+	logger.Println("Hello!")
+	if got := buf.String(); got != "[Info] Hello!\n" {
+		t.Errorf("after clearing got %q", got)
+	}
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	var lines int
+	logger := newEx(&buf, "[Info] ", 0)
+	logger.AddLineCallback(func([]byte) { lines++ })
+	child := logger.With("req", 42).With("user", "yax")
+	child.Info("done")
+	child.Println("Hello!")
+	logger.Info("parent")
+	want := "[Info] [INFO]: done req=42 user=yax\n[Info] Hello! req=42 user=yax\n[Info] [INFO]: parent\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+	if lines != 3 {
+		t.Errorf("parent callbacks saw %d lines, want 3", lines)
+	}
+}
+
+func TestInfoKV(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	kv := map[string]interface{}{"zone": "eu", "attempt": 3, "id": "a1", "ok": false}
+	for i := 0; i < 5; i++ {
+		logger.InfoKV("retry", kv)
+	}
+	logger.ErrKV("failed", map[string]interface{}{"b": 2, "a": 1})
+	want := strings.Repeat("[INFO]: retry attempt=3 id=a1 ok=false zone=eu\n", 5) + "[ERROR]: failed a=1 b=2\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestSetMaxFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetMaxFields(3)
+	child := logger.With("a", 1, "b", 2)
+	child.With("c", 3).InfoKV("kept", map[string]interface{}{"d": 4})
+	child.InfoKV("fits", map[string]interface{}{"c": 3})
+	logger.SetFormatter(&JSONFormatter{TimeFormat: "-"})
+	logger.With("a", 1, "b", 2, "c", 3, "d", 4, "e", 5).Info("json")
+	want := "[INFO]: kept a=1 b=2 c=3 _fields_truncated=1\n[INFO]: fits a=1 b=2 c=3\n" +
+		`{"time":"-","level":"INFO","msg":"json","a":1,"b":2,"c":3,"_fields_truncated":2}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+type testError struct{}
+
+func (*testError) Error() string { return "test error" }
+
+func TestSetNilRender(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	var nilErr *testError
+	var nilMap map[string]int
+	child := logger.With("v", nil, "err", error(nilErr), "ok", &testError{})
+	child.Info("default")
+	logger.SetNilRender("-")
+	child = logger.With("v", nil, "err", error(nilErr), "ok", &testError{}, "m", nilMap)
+	child.Info("text")
+	child.SetFormatter(&JSONFormatter{TimeFormat: "-"})
+	child.Info("json")
+	want := "[INFO]: default v=<nil> err=<nil> ok=test error\n" +
+		"[INFO]: text v=- err=- ok=test error m=-\n" +
+		`{"time":"-","level":"INFO","msg":"json","v":"-","err":"-","ok":"test error","m":"-"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,45 @@
+package glog
+
+/*
+A FileSink is a rotating log file shared by several loggers. The loggers
+only format their lines and hand them to the sink, which owns the file
+handle and accounts the size of all of them, so the file rotates once
+their total reaches the split size. A FileSink is safe for concurrent use.
+*/
+type FileSink struct {
+	l *Logger // owns the handle and the rotation, never formats
+}
+
+/*
+NewFileSink opens filename for sharing, splitSize and splitCount behave as in NewEx.
+It returns nil if the file cannot be opened.
+*/
+func NewFileSink(filename string, splitSize int, splitCount int) *FileSink {
+	l := NewEx(filename, "", 0, splitSize, splitCount)
+	if l == nil {
+		return nil
+	}
+	return &FileSink{l: l}
+}
+
+/*NewLogger creates a Logger writing to the sink.*/
+func (s *FileSink) NewLogger(prefix string, flag int) *Logger {
+	return newEx(s, prefix, flag)
+}
+
+/*Write writes one formatted line to the file and rotates it when due.*/
+func (s *FileSink) Write(p []byte) (int, error) {
+	s.l.mu.Lock()
+	defer s.l.mu.Unlock()
+	return s.l.write(p)
+}
+
+/*Rotate rotates the file right away, see Logger.Rotate.*/
+func (s *FileSink) Rotate() error {
+	return s.l.Rotate()
+}
+
+/*Close closes the file, the loggers writing to the sink must not be used afterwards.*/
+func (s *FileSink) Close() error {
+	return s.l.Close()
+}
@@ -0,0 +1,40 @@
+package glog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testG.log")
+	sink := NewFileSink(filename, 1, 5)
+	defer sink.Close()
+	sink.l.splitFileSize = 64
+	a := sink.NewLogger("[A] ", 0)
+	b := sink.NewLogger("[B] ", 0)
+
+	// every line is 11 bytes, the sixth one reaches the 64 bytes in total
+	for i := 0; i < 6; i++ {
+		if i%2 == 0 {
+			a.Printf("line-%d", i)
+		} else {
+			b.Printf("line-%d", i)
+		}
+	}
+	data, err := os.ReadFile(filename + ".0")
+	if err != nil {
+		t.Fatal("no rotation:", err)
+	}
+	want := "[A] line-0\n[B] line-1\n[A] line-2\n[B] line-3\n[A] line-4\n[B] line-5\n"
+	if string(data) != want {
+		t.Errorf("archive holds %q, want %q", data, want)
+	}
+	a.Printf("line-%d", 6)
+	if data, _ := os.ReadFile(filename); string(data) != "[A] line-6\n" {
+		t.Errorf("active file holds %q", data)
+	}
+	if _, err := os.Stat(filename + ".1"); err == nil {
+		t.Error("rotated more than once")
+	}
+}
@@ -0,0 +1,84 @@
+package glog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*flagNames lists the names of the flags in the order FormatFlags writes them.*/
+var flagNames = []struct {
+	name string
+	flag int
+}{
+	{"date", Ldate},
+	{"time", Ltime},
+	{"microseconds", Lmicroseconds},
+	{"longfile", Llongfile},
+	{"shortfile", Lshortfile},
+	{"utc", LUTC},
+	{"pid", Lpid},
+	{"methodonly", Lmethodonly},
+	{"seq", Lseq},
+	{"package", Lpackage},
+	{"entryid", Lentryid},
+}
+
+/*
+ParseFlags parses a comma separated list of flag names, e.g. "date,time,shortfile,utc",
+into the OR'ed flags. The names are date, time, microseconds, longfile, shortfile,
+utc, pid, methodonly, seq, package, entryid and stdflags for LstdFlags; they are case
+insensitive and "" yields 0. A hexadecimal number such as 0x800, as FormatFlags writes
+the bits without a name, sets those bits.
+*/
+func ParseFlags(s string) (int, error) {
+	flag := 0
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "stdflags" {
+			flag |= LstdFlags
+			continue
+		}
+		if strings.HasPrefix(name, "0x") {
+			bits, err := strconv.ParseUint(name[2:], 16, 63)
+			if err != nil {
+				return 0, fmt.Errorf("glog: unknown flag %q", name)
+			}
+			flag |= int(bits)
+			continue
+		}
+		found := false
+		for _, f := range flagNames {
+			if f.name == name {
+				flag |= f.flag
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("glog: unknown flag %q", name)
+		}
+	}
+	return flag, nil
+}
+
+/*
+FormatFlags returns the comma separated names of the flags set in flag, the
+reverse of ParseFlags. Bits without a name are written as a hexadecimal number.
+*/
+func FormatFlags(flag int) string {
+	var names []string
+	for _, f := range flagNames {
+		if flag&f.flag != 0 {
+			names = append(names, f.name)
+			flag &^= f.flag
+		}
+	}
+	if flag != 0 {
+		names = append(names, "0x"+strconv.FormatInt(int64(flag), 16))
+	}
+	return strings.Join(names, ",")
+}
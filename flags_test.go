@@ -0,0 +1,51 @@
+package glog
+
+import "testing"
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		s    string
+		flag int
+	}{
+		{"", 0},
+		{"date", Ldate},
+		{"date,time,shortfile,utc", Ldate | Ltime | Lshortfile | LUTC},
+		{" Date , TIME ,microseconds,longfile", Ldate | Ltime | Lmicroseconds | Llongfile},
+		{"stdflags,shortfile", LstdFlags | Lshortfile},
+	}
+	for _, tt := range tests {
+		flag, err := ParseFlags(tt.s)
+		if err != nil || flag != tt.flag {
+			t.Errorf("ParseFlags(%q) = %d, %v, want %d", tt.s, flag, err, tt.flag)
+		}
+	}
+	for _, s := range []string{"dates", "date,,bogus", "0x", "0xzz", "40"} {
+		if _, err := ParseFlags(s); err == nil {
+			t.Errorf("ParseFlags(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFormatFlags(t *testing.T) {
+	all := Ldate | Ltime | Lmicroseconds | Llongfile | Lshortfile | LUTC | Lpid | Lmethodonly | Lseq | Lpackage | Lentryid
+	for flag := 0; flag <= all; flag++ {
+		s := FormatFlags(flag)
+		back, err := ParseFlags(s)
+		if err != nil || back != flag {
+			t.Errorf("ParseFlags(FormatFlags(%d) = %q) = %d, %v", flag, s, back, err)
+		}
+	}
+	if s := FormatFlags(LstdFlags | Lshortfile); s != "date,time,shortfile" {
+		t.Errorf("FormatFlags = %q", s)
+	}
+	if s := FormatFlags(Ldate | 1<<11); s != "date,0x800" {
+		t.Errorf("FormatFlags with an unknown bit = %q", s)
+	}
+	if flag, err := ParseFlags(FormatFlags(Ldate | 1<<11 | 1<<20)); err != nil || flag != Ldate|1<<11|1<<20 {
+		t.Errorf("round trip with unknown bits = %#x, %v", flag, err)
+	}
+	// the values are stored in configurations, so a new flag must not renumber the older ones
+	if Lseq != 1<<8 || Lpackage != 1<<9 || Lentryid != 1<<10 {
+		t.Errorf("Lseq %#x, Lpackage %#x, Lentryid %#x", Lseq, Lpackage, Lentryid)
+	}
+}
@@ -0,0 +1,619 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*An Entry is a single logging event as handed to a Formatter.*/
+type Entry struct {
+	Time    time.Time // when the event was logged
+	Level   int       // DEBUG to FATAL, or NOLEVEL for the Print, Fatal and Panic families
+	Prefix  string    // prefix of the logger
+	File    string    // caller file name, only set when Llongfile or Lshortfile is specified
+	Line    int       // caller line number
+	Func    string    // caller function or method name without its package, only set when Lmethodonly is specified
+	Seq     uint64    // sequence number of the line, only set when Lseq is specified
+	ID      string    // identifier of the line, only set when Lentryid is specified
+	Package string    // caller package name, only set when Lpackage is specified
+	Message string    // the text to log, as passed to Output
+	Fields  []Field   // fields attached to the entry, the global fields are not included
+	Callers []Caller  // the frames above File and Line, innermost first, see SetHeaderFrames
+}
+
+/*A Caller is a frame of the call stack.*/
+type Caller struct {
+	File string
+	Line int
+}
+
+/*
+A Formatter renders entries for a Logger. Format appends the rendering of e,
+terminated by a newline, to buf and returns the extended buffer.
+The logger calls Format while holding its lock, so a Formatter shared
+by several loggers has to be safe for concurrent use; the built-in ones are.
+
+A custom Formatter can be measured like the built-in ones:
+
+	func BenchmarkMyFormatter(b *testing.B) {
+		f := &MyFormatter{}
+		e := &glog.Entry{Time: time.Now(), Level: glog.INFO, Message: "request done"}
+		var buf []byte
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = f.Format(buf[:0], e)
+		}
+	}
+*/
+type Formatter interface {
+	Format(buf []byte, e *Entry) []byte
+}
+
+/*
+TextFormatter renders entries as plain text lines: the header described by
+Flags, the level token and the message, followed by the entry fields as
+key=value pairs. It is the format used by a Logger without a Formatter.
+*/
+type TextFormatter struct {
+	Flags         int            // header properties, see Ldate and friends
+	Separator     string         // written after the date and after the time, the Logger default is a single space
+	Color         bool           // color the level token with ANSI escapes, for terminals only
+	LevelPad      int            // pads the level name to this width, on the left when positive and on the right when negative
+	Location      *time.Location // zone of the date and time, nil means the local time zone, LUTC takes precedence
+	HeaderPerLine bool           // repeat the header on each line of a multi-line message
+	Resolution    time.Duration  // truncate the time to a multiple of it, with Lmicroseconds showing its digits only
+	CompactLevel  bool           // write the level token as its first letter, "I " rather than "[INFO]: "
+	Bare          bool           // write neither the header nor the level token, only the message and the fields
+}
+
+/*levelColors holds the ANSI escapes coloring the level tokens.*/
+var levelColors = []string{"\x1b[36m", "\x1b[32m", "\x1b[33m", "\x1b[31m", "\x1b[35m"}
+
+const colorReset = "\x1b[0m"
+
+/*Format implements Formatter.*/
+func (f *TextFormatter) Format(buf []byte, e *Entry) []byte {
+	start := len(buf)
+	buf = f.appendHeader(buf, e)
+	end := len(buf)
+	buf = append(buf, e.Message...)
+	if f.HeaderPerLine {
+		buf = repeatHeader(buf, start, end)
+	}
+	return f.appendEnd(buf, len(buf)-end, e.Fields)
+}
+
+/*
+repeatHeader copies the header buf[start:end] after each newline of the
+message which follows it in buf, the newline ending the message excepted,
+so that every physical line of the message carries the header.
+*/
+func repeatHeader(buf []byte, start, end int) []byte {
+	msg := buf[end:]
+	if i := bytes.IndexByte(msg, '\n'); i < 0 || i == len(msg)-1 {
+		return buf
+	}
+	msg = append([]byte(nil), msg...)
+	header := buf[start:end] // left untouched by the appends below, which write past end
+	buf = buf[:end]
+	for {
+		i := bytes.IndexByte(msg, '\n')
+		if i < 0 || i == len(msg)-1 {
+			return append(buf, msg...)
+		}
+		buf = append(buf, msg[:i+1]...)
+		buf = append(buf, header...)
+		msg = msg[i+1:]
+	}
+}
+
+/*
+appendHeader writes log header to buf in following order:
+  - e.Prefix (if it's not blank),
+  - the global fields (if any are set),
+  - date and/or time (if corresponding flags are provided),
+  - the process ID (if Lpid is provided),
+  - the sequence number (if Lseq is provided),
+  - the identifier as id=ID (if Lentryid is provided and e.ID is set),
+  - file and line number (if corresponding flags are provided and e.File is set),
+  - the package name as pkg=name (if Lpackage is provided and e.Package is set),
+  - the function name (if Lmethodonly is provided and e.Func is set),
+  - the level token (if the entry has a level).
+
+Each part ends with a single space, or the Separator after the date, the time,
+the process ID, the sequence number and the identifier, so whatever the flags the parts, the
+level token and the message are spaced alike: "d.go:23: [INFO]: message".
+*/
+func (f *TextFormatter) appendHeader(buf []byte, e *Entry) []byte {
+	if f.Bare {
+		return buf
+	}
+	buf = append(buf, e.Prefix...)
+	appendGlobalFields(&buf)
+	if f.Flags&(Ldate|Ltime|Lmicroseconds) != 0 {
+		t := e.Time
+		if f.Flags&LUTC != 0 {
+			t = t.UTC()
+		} else if f.Location != nil {
+			t = t.In(f.Location)
+		}
+		if f.Resolution > 0 {
+			t = t.Truncate(f.Resolution)
+		}
+		if f.Flags&Ldate != 0 {
+			year, month, day := t.Date()
+			itoa(&buf, year, 4)
+			buf = append(buf, '/')
+			itoa(&buf, int(month), 2)
+			buf = append(buf, '/')
+			itoa(&buf, day, 2)
+			buf = append(buf, f.Separator...)
+		}
+		if f.Flags&(Ltime|Lmicroseconds) != 0 {
+			hour, min, sec := t.Clock()
+			itoa(&buf, hour, 2)
+			buf = append(buf, ':')
+			itoa(&buf, min, 2)
+			buf = append(buf, ':')
+			itoa(&buf, sec, 2)
+			if f.Flags&Lmicroseconds != 0 {
+				if digits := fractionDigits(f.Resolution); digits > 0 {
+					buf = append(buf, '.')
+					itoa(&buf, t.Nanosecond()/pow10[9-digits], digits)
+				}
+			}
+			buf = append(buf, f.Separator...)
+		}
+	}
+	if f.Flags&Lpid != 0 {
+		itoa(&buf, pid, -1)
+		buf = append(buf, f.Separator...)
+	}
+	if f.Flags&Lseq != 0 {
+		buf = append(buf, '#')
+		buf = strconv.AppendUint(buf, e.Seq, 10)
+		buf = append(buf, f.Separator...)
+	}
+	if f.Flags&Lentryid != 0 && e.ID != "" {
+		buf = append(buf, "id="...)
+		buf = append(buf, e.ID...)
+		buf = append(buf, f.Separator...)
+	}
+	if f.Flags&(Lshortfile|Llongfile) != 0 && e.File != "" {
+		file := e.File
+		if f.Flags&Lshortfile != 0 {
+			file = shortFile(file)
+		}
+		buf = append(buf, file...)
+		buf = append(buf, ':')
+		itoa(&buf, e.Line, -1)
+		for _, caller := range e.Callers {
+			file = caller.File
+			if f.Flags&Lshortfile != 0 {
+				file = shortFile(file)
+			}
+			buf = append(buf, "<-"...)
+			buf = append(buf, file...)
+			buf = append(buf, ':')
+			itoa(&buf, caller.Line, -1)
+		}
+		buf = append(buf, ": "...)
+	}
+	if f.Flags&Lpackage != 0 && e.Package != "" {
+		buf = append(buf, "pkg="...)
+		buf = append(buf, e.Package...)
+		buf = append(buf, ' ')
+	}
+	if f.Flags&Lmethodonly != 0 && e.Func != "" {
+		buf = append(buf, e.Func...)
+		buf = append(buf, ": "...)
+	}
+	if e.Level != NOLEVEL {
+		color := f.Color && e.Level >= 0 && e.Level < len(levelColors)
+		if color {
+			buf = append(buf, levelColors[e.Level]...)
+		}
+		name := levelName(e.Level)
+		if f.CompactLevel {
+			buf = append(buf, name[0])
+			if color {
+				buf = append(buf, colorReset...)
+			}
+			return append(buf, ' ')
+		}
+		buf = append(buf, '[')
+		for i := len(name); i < f.LevelPad; i++ {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, name...)
+		for i := len(name); i < -f.LevelPad; i++ {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, ']')
+		if color {
+			buf = append(buf, colorReset...)
+		}
+		buf = append(buf, ": "...)
+	}
+	return buf
+}
+
+/*
+HeaderWidth returns the width in bytes of the fixed part of the header the
+default text format writes with flag, before the message: the date, the time
+with its microseconds and the level token of the widest level name, exact for
+a logger with a level padding of 5 or -5, e.g. 29 for LstdFlags. The prefix,
+the global fields and the parts of variable width, such as the process ID,
+the sequence number, file:line and the function name, are not counted.
+*/
+func HeaderWidth(flag int) int {
+	width := len("[DEBUG]: ")
+	if flag&Ldate != 0 {
+		width += len("2009/01/23 ")
+	}
+	if flag&(Ltime|Lmicroseconds) != 0 {
+		width += len("01:23:23 ")
+	}
+	if flag&Lmicroseconds != 0 {
+		width += len(".123123")
+	}
+	return width
+}
+
+var pow10 = [...]int{1, 10, 100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9}
+
+/*
+fractionDigits returns how many digits of the second a time truncated to
+resolution carries, at most the 6 of Lmicroseconds: 1 for 100ms, 2 for 250ms
+and none from a second on. A resolution of zero or less keeps all 6.
+*/
+func fractionDigits(resolution time.Duration) int {
+	if resolution <= 0 {
+		return 6
+	}
+	for digits := 0; digits < 6; digits++ {
+		if resolution%(time.Second/time.Duration(pow10[digits])) == 0 {
+			return digits
+		}
+	}
+	return 6
+}
+
+/*
+appendEnd finishes a line whose message of msgLen bytes was just written to buf:
+it writes the fields and a newline, unless the message already ends with one.
+A message ending with \r\n ends with \n alone, so that it is not terminated twice.
+*/
+func (f *TextFormatter) appendEnd(buf []byte, msgLen int, fields []Field) []byte {
+	if msgLen > 0 && buf[len(buf)-1] == '\n' {
+		if msgLen > 1 && buf[len(buf)-2] == '\r' {
+			// a Windows line ending, \n alone ends the line as for the other messages
+			buf[len(buf)-2] = '\n'
+			buf = buf[:len(buf)-1]
+		}
+		if len(fields) == 0 {
+			return buf
+		}
+		buf = buf[:len(buf)-1]
+	}
+	for _, field := range fields {
+		buf = append(buf, ' ')
+		buf = append(buf, field.Key...)
+		buf = append(buf, '=')
+		buf = appendValue(buf, field.Value)
+	}
+	return append(buf, '\n')
+}
+
+/*
+JSONFormatter renders each entry as one JSON object per line with the keys
+"time", "seq", "id", "level", "prefix", "file", "line", "callers", "pkg", "func" and "msg" followed by the global
+fields and the entry fields. Keys without a value for the entry, like the
+level of a Print call or the file without Lshortfile, are left out.
+With Indent set each record spans several lines, one per key, for reading
+the output during local development.
+*/
+type JSONFormatter struct {
+	TimeFormat string // layout of the "time" value, "" means time.RFC3339Nano
+	UTC        bool   // render the time in UTC rather than in the local time zone
+	Indent     string // indentation of the keys, e.g. "  ", "" keeps each record on a single line
+	LevelNum   bool   // add the numeric level, DEBUG 0 to FATAL 4, as "level_num" after "level"
+}
+
+/*Format implements Formatter.*/
+func (f *JSONFormatter) Format(buf []byte, e *Entry) []byte {
+	t := e.Time
+	if f.UTC {
+		t = t.UTC()
+	}
+	layout := f.TimeFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	start := len(buf)
+	buf = append(buf, `{"time":"`...)
+	buf = t.AppendFormat(buf, layout)
+	buf = append(buf, '"')
+	if e.Seq != 0 {
+		buf = append(buf, `,"seq":`...)
+		buf = strconv.AppendUint(buf, e.Seq, 10)
+	}
+	if e.ID != "" {
+		buf = append(buf, `,"id":`...)
+		buf = appendJSONString(buf, e.ID)
+	}
+	if e.Level != NOLEVEL {
+		buf = append(buf, `,"level":`...)
+		buf = appendJSONString(buf, levelName(e.Level))
+		if f.LevelNum {
+			buf = append(buf, `,"level_num":`...)
+			buf = strconv.AppendInt(buf, int64(e.Level), 10)
+		}
+	}
+	if e.Prefix != "" {
+		buf = append(buf, `,"prefix":`...)
+		buf = appendJSONString(buf, e.Prefix)
+	}
+	if e.File != "" {
+		buf = append(buf, `,"file":`...)
+		buf = appendJSONString(buf, e.File)
+		buf = append(buf, `,"line":`...)
+		buf = strconv.AppendInt(buf, int64(e.Line), 10)
+	}
+	if len(e.Callers) > 0 {
+		buf = append(buf, `,"callers":[`...)
+		for i, caller := range e.Callers {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, caller.File+":"+strconv.Itoa(caller.Line))
+		}
+		buf = append(buf, ']')
+	}
+	if e.Package != "" {
+		buf = append(buf, `,"pkg":`...)
+		buf = appendJSONString(buf, e.Package)
+	}
+	if e.Func != "" {
+		buf = append(buf, `,"func":`...)
+		buf = appendJSONString(buf, e.Func)
+	}
+	buf = append(buf, `,"msg":`...)
+	buf = appendJSONString(buf, trimNewline(e.Message))
+	for _, field := range GlobalFields() {
+		buf = appendJSONField(buf, field)
+	}
+	for _, field := range e.Fields {
+		buf = appendJSONField(buf, field)
+	}
+	buf = append(buf, '}')
+	if f.Indent != "" {
+		var indented bytes.Buffer
+		if json.Indent(&indented, buf[start:], "", f.Indent) == nil {
+			buf = append(buf[:start], indented.Bytes()...)
+		}
+	}
+	return append(buf, '\n')
+}
+
+/*
+ParseJSONEntry parses a line written by JSONFormatter back into an Entry, e.g.
+to write it in another format with LogEntry. The time has to be in the
+default RFC 3339 layout. The keys other than those of the entry become its
+fields, the global fields of the writer included, with the integers as
+int64 and the other numbers as float64.
+*/
+func ParseJSONEntry(line []byte) (Entry, error) {
+	e := Entry{Level: NOLEVEL}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil {
+		return Entry{}, err
+	} else if tok != json.Delim('{') {
+		return Entry{}, errors.New("glog: the JSON entry is not an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return Entry{}, err
+		}
+		key, _ := tok.(string)
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return Entry{}, err
+		}
+		s, isString := v.(string)
+		ok := true
+		switch key {
+		case "time":
+			if e.Time, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				return Entry{}, err
+			}
+		case "level":
+			e.Level, ok = parseLevel(s)
+		case "level_num":
+			_, err = jsonInt(v) // implied by "level"
+			ok = err == nil
+		case "prefix":
+			e.Prefix, ok = s, isString
+		case "file":
+			e.File, ok = s, isString
+		case "seq":
+			var n int64
+			n, err = jsonInt(v)
+			e.Seq, ok = uint64(n), err == nil && n > 0
+		case "id":
+			e.ID, ok = s, isString
+		case "pkg":
+			e.Package, ok = s, isString
+		case "func":
+			e.Func, ok = s, isString
+		case "msg":
+			e.Message, ok = s, isString
+		case "line":
+			var n int64
+			n, err = jsonInt(v)
+			e.Line, ok = int(n), err == nil
+		case "callers":
+			callers, _ := v.([]interface{})
+			for _, c := range callers {
+				s, _ := c.(string)
+				i := strings.LastIndexByte(s, ':')
+				n, err := strconv.Atoi(s[i+1:])
+				if i < 0 || err != nil {
+					ok = false
+					break
+				}
+				e.Callers = append(e.Callers, Caller{File: s[:i], Line: n})
+			}
+		default:
+			if number, isNumber := v.(json.Number); isNumber {
+				if n, err := number.Int64(); err == nil {
+					v = n
+				} else if f, err := number.Float64(); err == nil {
+					v = f
+				}
+			}
+			e.Fields = append(e.Fields, Field{Key: key, Value: v})
+		}
+		if !ok {
+			return Entry{}, fmt.Errorf("glog: invalid %q in the JSON entry", key)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+/*jsonInt converts a decoded JSON number to an int64.*/
+func jsonInt(v interface{}) (int64, error) {
+	number, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	return number.Int64()
+}
+
+/*appendJSONField writes ,"key":value to buf.*/
+func appendJSONField(buf []byte, field Field) []byte {
+	buf = append(buf, ',')
+	buf = appendJSONString(buf, field.Key)
+	buf = append(buf, ':')
+	return appendJSONValue(buf, field.Value)
+}
+
+/*
+appendJSONValue writes v as JSON. Errors and durations render as strings, a nil
+error as null, and values encoding/json cannot marshal fall back to their fmt.Sprint
+string.
+*/
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONString(buf, v)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case error:
+		if isNil(v) {
+			return append(buf, "null"...)
+		}
+		return appendJSONString(buf, v.Error())
+	case time.Duration:
+		return appendJSONString(buf, v.String())
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(buf, fmt.Sprint(v))
+	}
+	return append(buf, b...)
+}
+
+/*appendJSONString writes s as a quoted JSON string, invalid UTF-8 becomes U+FFFD.*/
+func appendJSONString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, "\ufffd"...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
+
+/*trimNewline strips the newline terminating a message, \n or \r\n, if any.*/
+func trimNewline(msg string) string {
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+		if len(msg) > 0 && msg[len(msg)-1] == '\r' {
+			msg = msg[:len(msg)-1]
+		}
+	}
+	return msg
+}
+
+/*shortFile returns the final element of a file path.*/
+func shortFile(file string) string {
+	for i := len(file) - 1; i > 0; i-- {
+		if file[i] == '/' {
+			return file[i+1:]
+		}
+	}
+	return file
+}
+
+/*parseLevel returns the level named name, as written by levelName.*/
+func parseLevel(name string) (int, bool) {
+	for level, s := range levelStr {
+		if s == name {
+			return level, true
+		}
+	}
+	level, err := strconv.Atoi(name)
+	return level, err == nil
+}
+
+/*levelName returns the name of level as used in the level token.*/
+func levelName(level int) string {
+	if level < 0 || level >= len(levelStr) {
+		return strconv.Itoa(level)
+	}
+	return levelStr[level]
+}
@@ -0,0 +1,533 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var benchEntry = &Entry{
+	Time:    time.Date(2009, 1, 23, 1, 23, 23, 123123000, time.UTC),
+	Level:   INFO,
+	Prefix:  "[Info] ",
+	File:    "/a/b/c/d.go",
+	Line:    23,
+	Message: "abcdefghijklmnopqrstuvwxyz0123456789你好，我是测试日志~!@#$%^&*()_+{}|:",
+	Fields:  []Field{{Key: "user", Value: "yax"}, {Key: "count", Value: 5}},
+}
+
+func TestTextFormatter(t *testing.T) {
+	f := &TextFormatter{Flags: Ldate | Ltime | Lmicroseconds | Lshortfile | LUTC, Separator: " "}
+	got := string(f.Format(nil, benchEntry))
+	want := "[Info] 2009/01/23 01:23:23.123123 d.go:23: [INFO]: " + benchEntry.Message + " user=yax count=5\n"
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	var def, text bytes.Buffer
+	logger := newEx(&def, "[Info] ", 0)
+	logger.Info("%s-%d", "abc", 123)
+	logger = newEx(&text, "[Info] ", 0)
+	logger.SetFormatter(&TextFormatter{Separator: " "})
+	logger.Info("%s-%d", "abc", 123)
+	if def.String() != "[Info] [INFO]: abc-123\n" || text.String() != def.String() {
+		t.Errorf("default %q, TextFormatter %q", def.String(), text.String())
+	}
+}
+
+func TestHeaderSpacing(t *testing.T) {
+	e := &Entry{Time: benchEntry.Time, Level: INFO, File: "/a/b/c/d.go", Line: 23, Message: "message"}
+	tests := []struct {
+		prefix string
+		flags  int
+		level  int
+		want   string
+	}{
+		{"", 0, INFO, "[INFO]: message\n"},
+		{"", 0, NOLEVEL, "message\n"},
+		{"", Lshortfile, INFO, "d.go:23: [INFO]: message\n"},
+		{"", Lshortfile, NOLEVEL, "d.go:23: message\n"},
+		{"", LstdFlags | LUTC, INFO, "2009/01/23 01:23:23 [INFO]: message\n"},
+		{"", LstdFlags | LUTC | Lshortfile, INFO, "2009/01/23 01:23:23 d.go:23: [INFO]: message\n"},
+		{"[app] ", 0, INFO, "[app] [INFO]: message\n"},
+		{"[app] ", Ltime | LUTC | Lshortfile, NOLEVEL, "[app] 01:23:23 d.go:23: message\n"},
+	}
+	for _, tt := range tests {
+		entry := *e
+		entry.Prefix = tt.prefix
+		entry.Level = tt.level
+		f := &TextFormatter{Flags: tt.flags, Separator: " "}
+		if got := string(f.Format(nil, &entry)); got != tt.want {
+			t.Errorf("prefix %q flags %d level %d: got %q, want %q", tt.prefix, tt.flags, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLpid(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Ltime|Lpid)
+	logger.Println("Hello!")
+	fields := strings.Fields(buf.String())
+	if len(fields) != 3 || fields[1] != strconv.Itoa(os.Getpid()) {
+		t.Errorf("got %q, want the PID %d after the time", buf.String(), os.Getpid())
+	}
+}
+
+type testServer struct {
+	logger *Logger
+}
+
+func (s *testServer) handleRequest() {
+	s.logger.Info("ok")
+	func() {
+		s.logger.Println("deferred")
+	}()
+}
+
+func TestLmethodonly(t *testing.T) {
+	var buf bytes.Buffer
+	s := &testServer{logger: newEx(&buf, "", Lmethodonly)}
+	s.handleRequest()
+	want := "handleRequest: [INFO]: ok\nhandleRequest: deferred\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	s.logger.SetFlags(Lshortfile | Lmethodonly)
+	s.handleRequest()
+	if got := strings.SplitN(buf.String(), "\n", 2)[0]; !strings.HasPrefix(got, "formatter_test.go:") || !strings.HasSuffix(got, ": handleRequest: [INFO]: ok") {
+		t.Errorf("with Lshortfile got %q", got)
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Lshortfile)
+	logger.SetFormatter(&JSONFormatter{})
+	logger.Err("failed %d times\n", 3)
+	logger.Println("Hello!")
+
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), buf.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if record["level"] != "ERROR" || record["msg"] != "failed 3 times" || record["line"] == nil {
+		t.Errorf("unexpected record %v", record)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, record["time"].(string)); err != nil {
+		t.Errorf("bad time: %v", err)
+	}
+	record = nil
+	if err := json.Unmarshal(lines[1], &record); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[1], err)
+	}
+	if _, ok := record["level"]; ok || record["msg"] != "Hello!" {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	line := (&JSONFormatter{}).Format(nil, &Entry{Time: benchEntry.Time, Level: NOLEVEL, Message: "a\"b\x01\xff",
+		Fields: []Field{{Key: "n", Value: nil}, {Key: "ch", Value: make(chan int)}}})
+	record = nil
+	if err := json.Unmarshal(line, &record); err != nil {
+		t.Fatalf("invalid JSON %q: %v", line, err)
+	}
+	if _, ok := record["ch"].(string); !ok || record["msg"] != "a\"b\x01\ufffd" || record["n"] != nil {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestJSONFormatterIndent(t *testing.T) {
+	for _, indent := range []string{"", "  "} {
+		f := &JSONFormatter{Indent: indent}
+		out := f.Format(nil, benchEntry)
+		var record map[string]interface{}
+		if err := json.Unmarshal(out, &record); err != nil {
+			t.Fatalf("indent %q: invalid JSON %q: %v", indent, out, err)
+		}
+		if record["msg"] != benchEntry.Message || record["count"] != 5.0 {
+			t.Errorf("indent %q: unexpected record %v", indent, record)
+		}
+		lines := strings.Count(string(out), "\n")
+		if indent == "" && lines != 1 || indent != "" && lines != 10 {
+			t.Errorf("indent %q: %d lines in %q", indent, lines, out)
+		}
+	}
+}
+
+func TestParseJSONEntry(t *testing.T) {
+	line := (&JSONFormatter{UTC: true}).Format(nil, benchEntry)
+	e, err := ParseJSONEntry(line)
+	if err != nil {
+		t.Fatalf("ParseJSONEntry(%q): %v", line, err)
+	}
+	if !e.Time.Equal(benchEntry.Time) || e.Level != INFO || e.File != benchEntry.File || e.Message != benchEntry.Message {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Ldate|Ltime|Lmicroseconds|Lshortfile|LUTC)
+	if err := logger.LogEntry(e); err != nil {
+		t.Fatal(err)
+	}
+	want := string((&TextFormatter{Flags: Ldate | Ltime | Lmicroseconds | Lshortfile | LUTC, Separator: " "}).Format(nil, benchEntry))
+	if buf.String() != want {
+		t.Errorf("re-emitted %q\nwant       %q", buf.String(), want)
+	}
+
+	for _, bad := range []string{`[]`, `{"time":"yesterday"}`, `{"level":"LOUD"}`, `{"line":"x"}`, `{"msg":1`} {
+		if _, err := ParseJSONEntry([]byte(bad)); err == nil {
+			t.Errorf("ParseJSONEntry(%s) succeeded", bad)
+		}
+	}
+}
+
+func BenchmarkTextFormatter(b *testing.B) {
+	f := &TextFormatter{Flags: LstdFlags | Lshortfile, Separator: " "}
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = f.Format(buf[:0], benchEntry)
+	}
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	f := &JSONFormatter{}
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = f.Format(buf[:0], benchEntry)
+	}
+}
+
+func TestLevelPadding(t *testing.T) {
+	for _, tt := range []struct {
+		width int
+		want  string
+	}{
+		{-5, "[DEBUG]: a\n[INFO ]: a\n[WARN ]: a\n[ERROR]: a\n"},
+		{5, "[DEBUG]: a\n[ INFO]: a\n[ WARN]: a\n[ERROR]: a\n"},
+		{0, "[DEBUG]: a\n[INFO]: a\n[WARN]: a\n[ERROR]: a\n"},
+	} {
+		var buf bytes.Buffer
+		logger := newEx(&buf, "", 0)
+		logger.SetLevelPadding(tt.width)
+		logger.Debug("a")
+		logger.Info("a")
+		logger.Warn("a")
+		logger.Err("a")
+		if buf.String() != tt.want {
+			t.Errorf("width %d: got %q, want %q", tt.width, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestSetCompactLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Lshortfile)
+	logger.SetCompactLevel(true)
+	logger.SetLevelPadding(-5)
+	logger.Debug("a")
+	logger.Info("a")
+	logger.Warn("a")
+	logger.Err("a")
+	logger.Print("a")
+	want := "formatter_test.go:239: D a\n" + "formatter_test.go:240: I a\n" + "formatter_test.go:241: W a\n" +
+		"formatter_test.go:242: E a\n" + "formatter_test.go:243: a\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	f := &TextFormatter{CompactLevel: true, Color: true}
+	if got := string(f.Format(nil, &Entry{Level: FATAL, Message: "a"})); got != "\x1b[35mF\x1b[0m a\n" {
+		t.Errorf("FATAL: got %q", got)
+	}
+	logger.SetCompactLevel(false)
+	buf.Reset()
+	logger.Info("a")
+	if !strings.HasSuffix(buf.String(), " [INFO ]: a\n") {
+		t.Errorf("after SetCompactLevel(false): got %q", buf.String())
+	}
+}
+
+type panickingFormatter struct{}
+
+func (panickingFormatter) Format([]byte, *Entry) []byte { panic("weird field") }
+
+func TestFormatterPanic(t *testing.T) {
+	var buf, diag bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetInternalErrorWriter(&diag)
+	logger.SetFormatter(panickingFormatter{})
+	logger.AddLineCallback(func([]byte) { panic("broken hook") })
+	logger.With("k", 1).Info("survived")
+	logger.Info("again")
+	if buf.String() != "[INFO]: survived\n[INFO]: again\n" {
+		t.Errorf("fell back to %q", buf.String())
+	}
+	if !strings.Contains(diag.String(), "glog: formatter panicked: weird field\n") ||
+		!strings.Contains(diag.String(), "glog: line callback panicked: broken hook\n") {
+		t.Errorf("diagnostics %q", diag.String())
+	}
+}
+
+func TestSetLocation(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", LstdFlags)
+	logger.SetLocation(time.FixedZone("UTC+8", 8*3600))
+	now = func() time.Time { return time.Date(2024, 12, 31, 20, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	logger.Println("shanghai")
+	logger.SetFlags(LstdFlags | LUTC)
+	logger.Println("utc")
+	want := "2025/01/01 04:30:00 shanghai\n2024/12/31 20:30:00 utc\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestSetHeaderPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[app] ", 0)
+	logger.SetHeaderPerLine(true)
+	logger.With("id", 7).Err("panic: boom\n\tmain.go:12\n\tmain.go:40\n")
+	logger.Info("single")
+	logger.OutputBytes(1, []byte("raw\nbytes"))
+	want := "[app] [ERROR]: panic: boom\n[app] [ERROR]: \tmain.go:12\n[app] [ERROR]: \tmain.go:40 id=7\n" +
+		"[app] [INFO]: single\n[app] raw\n[app] bytes\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+
+	f := &TextFormatter{HeaderPerLine: true}
+	if got := string(f.Format(nil, &Entry{Level: WARNING, Message: "a\nb"})); got != "[WARN]: a\n[WARN]: b\n" {
+		t.Errorf("TextFormatter got %q", got)
+	}
+}
+
+func TestLseq(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Lseq)
+	logger.Info("one")
+	logger.Println("two")
+	logger.SetFormatter(&JSONFormatter{})
+	logger.Info("three")
+	lines := strings.SplitN(buf.String(), "\n", 3)
+	if lines[0] != "#1 [INFO]: one" || lines[1] != "#2 two" {
+		t.Errorf("got %q", buf.String())
+	}
+	if e, err := ParseJSONEntry([]byte(lines[2])); err != nil || e.Seq != 3 || e.Message != "three" {
+		t.Errorf("JSON record %q parsed to %+v, %v", lines[2], e, err)
+	}
+
+	buf.Reset()
+	logger.SetFormatter(nil)
+	const goroutines, count = 10, 1000
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				logger.Println("x")
+			}
+		}()
+	}
+	wg.Wait()
+	lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines*count {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for i, line := range lines {
+		// the lines are written in the order of their numbers, from 4 on
+		if want := "#" + strconv.Itoa(i+4) + " x"; line != want {
+			t.Fatalf("line %d is %q, want %q", i, line, want)
+		}
+	}
+
+	// the children number their lines along with the logger they write through
+	buf.Reset()
+	logger = newEx(&buf, "", Lseq)
+	child := logger.With("k", 1)
+	logger.Println("a")
+	child.Println("b")
+	child.With("k", 2).Println("c")
+	logger.Println("d")
+	if want := "#1 a\n#2 b k=1\n#3 c k=1 k=2\n#4 d\n"; buf.String() != want {
+		t.Errorf("with children got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLentryid(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Lentryid)
+	const count = 1000
+	for i := 0; i < count; i++ {
+		logger.Println("x")
+	}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		id := strings.TrimSuffix(strings.TrimPrefix(line, "id="), " x")
+		if len(id) != 12 || strings.Trim(id, "0123456789abcdef") != "" || "id="+id+" x" != line {
+			t.Fatalf("line %q does not carry 12 hex digits", line)
+		}
+		if seen[id] {
+			t.Fatalf("id %s written twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != count {
+		t.Fatalf("got %d ids", len(seen))
+	}
+
+	buf.Reset()
+	n := 0
+	logger.SetEntryIDFunc(func() string { n++; return "e" + strconv.Itoa(n) })
+	logger.Info("one")
+	logger.With("k", 1).Info("two")
+	logger.SetFormatter(&JSONFormatter{})
+	logger.Info("three")
+	lines := strings.SplitN(buf.String(), "\n", 3)
+	if lines[0] != "id=e1 [INFO]: one" || lines[1] != "id=e2 [INFO]: two k=1" {
+		t.Errorf("got %q", buf.String())
+	}
+	if e, err := ParseJSONEntry([]byte(lines[2])); err != nil || e.ID != "e3" || e.Message != "three" {
+		t.Errorf("JSON record %q parsed to %+v, %v", lines[2], e, err)
+	}
+	if got := string((&LogfmtFormatter{}).Format(nil, &Entry{Level: NOLEVEL, ID: "e4", Message: "m"})); !strings.Contains(got, " id=e4 msg=m") {
+		t.Errorf("LogfmtFormatter got %q", got)
+	}
+
+	buf.Reset()
+	logger.SetFormatter(nil)
+	logger.SetEntryIDFunc(nil)
+	logger.SetFlags(0)
+	logger.Println("none")
+	if got := buf.String(); got != "none\n" {
+		t.Errorf("without Lentryid got %q", got)
+	}
+}
+
+func TestJSONFormatterLevelNum(t *testing.T) {
+	f := &JSONFormatter{TimeFormat: "-", LevelNum: true}
+	line := f.Format(nil, &Entry{Level: ERROR, Message: "failed"})
+	if want := `{"time":"-","level":"ERROR","level_num":3,"msg":"failed"}` + "\n"; string(line) != want {
+		t.Errorf("got  %q\nwant %q", line, want)
+	}
+	if line := f.Format(nil, &Entry{Level: NOLEVEL, Message: "plain"}); bytes.Contains(line, []byte("level")) {
+		t.Errorf("an entry without a level got %q", line)
+	}
+	f.TimeFormat = ""
+	e, err := ParseJSONEntry(f.Format(nil, &Entry{Time: time.Now(), Level: WARNING, Message: "m"}))
+	if err != nil || e.Level != WARNING || len(e.Fields) != 0 {
+		t.Errorf("parsed to %+v, %v", e, err)
+	}
+}
+
+func TestSetTimeResolution(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Ltime|Lmicroseconds|LUTC)
+	now = func() time.Time { return time.Date(2024, 5, 1, 10, 20, 37, 987654321, time.UTC) }
+	defer func() { now = time.Now }()
+	for _, tt := range []struct {
+		resolution time.Duration
+		want       string
+	}{
+		{0, "10:20:37.987654"},
+		{100 * time.Millisecond, "10:20:37.9"},
+		{250 * time.Millisecond, "10:20:37.75"},
+		{time.Millisecond, "10:20:37.987"},
+		{time.Second, "10:20:37"},
+		{15 * time.Second, "10:20:30"},
+	} {
+		buf.Reset()
+		logger.SetTimeResolution(tt.resolution)
+		logger.Println("x")
+		if want := tt.want + " x\n"; buf.String() != want {
+			t.Errorf("resolution %v: got %q, want %q", tt.resolution, buf.String(), want)
+		}
+	}
+	buf.Reset()
+	logger.SetFlags(Ltime | LUTC)
+	logger.SetTimeResolution(100 * time.Millisecond)
+	if logger.Println("x"); buf.String() != "10:20:37 x\n" {
+		t.Errorf("without Lmicroseconds got %q", buf.String())
+	}
+}
+
+func TestLpackage(t *testing.T) {
+	var buf bytes.Buffer
+	s := &testServer{logger: newEx(&buf, "", Lpackage|Lmethodonly)}
+	s.handleRequest()
+	want := "pkg=glog handleRequest: [INFO]: ok\npkg=glog handleRequest: deferred\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+	for fn, want := range map[string]string{
+		"github.com/acme/cache.(*LRU).Get":   "cache",
+		"github.com/acme/cache.New.func1.2":  "cache",
+		"github.com/acme/cache.Map[...].Get": "cache",
+		"main.main":                          "main",
+		"main.(*server).serve":               "main",
+		"":                                   "???",
+	} {
+		if got := packageName(fn); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", fn, got, want)
+		}
+	}
+}
+
+func TestHeaderWidth(t *testing.T) {
+	for _, tt := range []struct {
+		flag  int
+		width int
+	}{
+		{0, 9},
+		{Ldate, 20},
+		{Ltime, 18},
+		{LstdFlags, 29},
+		{LstdFlags | Lmicroseconds, 36},
+		{Lmicroseconds | LUTC, 25},
+	} {
+		if got := HeaderWidth(tt.flag); got != tt.width {
+			t.Errorf("flags %s: got %d, want %d", FormatFlags(tt.flag), got, tt.width)
+		}
+		var buf bytes.Buffer
+		logger := newEx(&buf, "", tt.flag)
+		logger.SetLevelPadding(5)
+		logger.Info("x")
+		logger.Warn("x")
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if strings.Index(line, "x") != tt.width {
+				t.Errorf("flags %s: the message of %q is not at %d", FormatFlags(tt.flag), line, tt.width)
+			}
+		}
+	}
+}
+
+func TestCRLFMessage(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.Info("done\r\n")
+	logger.With("k", 1).Info("done\r\n")
+	logger.Output(2, "plain\r\n")
+	logger.Info("\r\n")
+	logger.Info("kept\r in the middle\n")
+	if want := "[INFO]: done\n[INFO]: done k=1\nplain\n[INFO]: \n[INFO]: kept\r in the middle\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	logger.SetFormatter(&JSONFormatter{})
+	logger.Info("done\r\n")
+	if !strings.Contains(buf.String(), `"msg":"done"`) {
+		t.Errorf("JSON: got %q", buf.String())
+	}
+}
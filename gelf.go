@@ -0,0 +1,131 @@
+package glog
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*GELF chunking limits, see the Graylog GELF specification.*/
+const (
+	GELF_CHUNK_SIZE = 1420 // default datagram size, safe on WAN paths
+	gelfMaxChunks   = 128
+	gelfChunkHeader = 12 // magic bytes, message id, sequence number and count
+)
+
+var errGELFTooLarge = errors.New("glog: GELF message exceeds 128 chunks")
+
+/*
+A GELFHandler sends entries to Graylog as GELF 1.1 JSON payloads over UDP,
+splitting the payloads larger than ChunkSize into GELF chunks. The fields
+of the entry, attached with With, become additional "_key" fields. It is
+safe for concurrent use.
+*/
+type GELFHandler struct {
+	ChunkSize int // maximum datagram size, GELF_CHUNK_SIZE when zero
+	Host      string
+	conn      net.Conn
+}
+
+/*NewGELF returns a Handler sending entries to the GELF UDP input at addr, e.g. "graylog:12201".*/
+func NewGELF(addr string) (*GELFHandler, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	return &GELFHandler{ChunkSize: GELF_CHUNK_SIZE, Host: host, conn: conn}, nil
+}
+
+/*Handle implements Handler.*/
+func (h *GELFHandler) Handle(e *Entry) error {
+	payload := h.appendPayload(nil, e)
+	chunkSize := h.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = GELF_CHUNK_SIZE
+	}
+	if len(payload) <= chunkSize {
+		_, err := h.conn.Write(payload)
+		return err
+	}
+	dataSize := chunkSize - gelfChunkHeader
+	count := (len(payload) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return errGELFTooLarge
+	}
+	chunk := make([]byte, 0, chunkSize)
+	chunk = append(chunk, 0x1e, 0x0f)
+	chunk = append(chunk, make([]byte, 8)...)
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk = append(chunk[:10], byte(i), byte(count))
+		chunk = append(chunk, payload[i*dataSize:end]...)
+		if _, err := h.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*Close closes the UDP socket.*/
+func (h *GELFHandler) Close() error {
+	return h.conn.Close()
+}
+
+/*appendPayload writes the GELF JSON payload of e to buf.*/
+func (h *GELFHandler) appendPayload(buf []byte, e *Entry) []byte {
+	msg := trimNewline(e.Message)
+	short := msg
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		short = msg[:i]
+	}
+	buf = append(buf, `{"version":"1.1","host":`...)
+	buf = appendJSONString(buf, h.Host)
+	buf = append(buf, `,"short_message":`...)
+	buf = appendJSONString(buf, short)
+	if short != msg {
+		buf = append(buf, `,"full_message":`...)
+		buf = appendJSONString(buf, msg)
+	}
+	buf = append(buf, `,"timestamp":`...)
+	buf = strconv.AppendFloat(buf, float64(e.Time.UnixNano()/1e3)/1e6, 'f', 6, 64)
+	buf = append(buf, `,"level":`...)
+	buf = strconv.AppendInt(buf, int64(SyslogSeverity(e.Level)), 10)
+	if e.File != "" {
+		buf = append(buf, `,"_file":`...)
+		buf = appendJSONString(buf, e.File)
+		buf = append(buf, `,"_line":`...)
+		buf = strconv.AppendInt(buf, int64(e.Line), 10)
+	}
+	for _, field := range GlobalFields() {
+		buf = appendGELFField(buf, field)
+	}
+	for _, field := range e.Fields {
+		buf = appendGELFField(buf, field)
+	}
+	return append(buf, '}')
+}
+
+/*appendGELFField writes an additional field, the key limited to the characters GELF allows.*/
+func appendGELFField(buf []byte, field Field) []byte {
+	key := []byte("_")
+	for _, c := range []byte(field.Key) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			c = '_'
+		}
+		key = append(key, c)
+	}
+	if string(key) == "_id" {
+		key = append(key, '_') // reserved by GELF
+	}
+	return appendJSONField(buf, Field{Key: string(key), Value: field.Value})
+}
@@ -0,0 +1,101 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listenGELF(t *testing.T) (net.PacketConn, *GELFHandler) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no UDP:", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	h, err := NewGELF(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return conn, h
+}
+
+func readGELF(t *testing.T, conn net.PacketConn) map[string]interface{} {
+	var payload []byte
+	chunks := map[byte][]byte{}
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := buf[:n]
+		if !bytes.HasPrefix(p, []byte{0x1e, 0x0f}) {
+			payload = append([]byte(nil), p...)
+			break
+		}
+		chunks[p[10]] = append([]byte(nil), p[12:]...)
+		if len(chunks) == int(p[11]) {
+			for i := 0; i < len(chunks); i++ {
+				payload = append(payload, chunks[byte(i)]...)
+			}
+			break
+		}
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		t.Fatalf("invalid GELF payload %q: %v", payload, err)
+	}
+	return record
+}
+
+func TestGELF(t *testing.T) {
+	conn, h := listenGELF(t)
+	h.Host = "testhost"
+	logger := newEx(nil, "", Lshortfile)
+	logger.SetHandler(h)
+	child := logger.With("user", "yax", "id", 7)
+
+	child.Err("boom %d", 1)
+	record := readGELF(t, conn)
+	if record["version"] != "1.1" || record["host"] != "testhost" || record["short_message"] != "boom 1" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if record["level"] != 3.0 || record["_user"] != "yax" || record["_id_"] != 7.0 || record["_file"] == nil {
+		t.Errorf("unexpected record %v", record)
+	}
+	if ts, ok := record["timestamp"].(float64); !ok || time.Since(time.Unix(int64(ts), 0)) > time.Minute {
+		t.Errorf("bad timestamp %v", record["timestamp"])
+	}
+
+	levels := map[int]float64{DEBUG: 7, INFO: 6, WARNING: 4, ERROR: 3, FATAL: 2, NOLEVEL: 6}
+	for level, severity := range levels {
+		logger.Log(level).Msg("level")
+		if got := readGELF(t, conn)["level"]; got != severity {
+			t.Errorf("level %d sent as %v, want %v", level, got, severity)
+		}
+	}
+}
+
+func TestGELFChunking(t *testing.T) {
+	conn, h := listenGELF(t)
+	h.ChunkSize = 512
+	logger := newEx(nil, "", 0)
+	logger.SetHandler(h)
+	long := strings.Repeat("abcdefghijklmnopqrstuvwxyz", 200)
+
+	logger.Println("first line\n" + long)
+	record := readGELF(t, conn)
+	if record["short_message"] != "first line" || record["full_message"] != "first line\n"+long {
+		t.Errorf("reassembled record has short_message %q", record["short_message"])
+	}
+
+	h.ChunkSize = 64
+	if err := logger.Output(1, strings.Repeat(long, 2)); err != errGELFTooLarge {
+		t.Errorf("got %v, want errGELFTooLarge", err)
+	}
+}
@@ -1,12 +1,22 @@
 package glog
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 /*
@@ -33,6 +43,11 @@ const (
 	Llongfile                     // full file name and line number: /a/b/c/d.go:23
 	Lshortfile                    // final file name element and line number: d.go:23. overrides Llongfile
 	LUTC                          // if Ldate or Ltime is set, use UTC rather than the local time zone
+	Lpid                          // the process ID after the date and time: 01:23:23 4242
+	Lmethodonly                   // the calling function or method without its package: handleRequest
+	Lseq                          // the sequence number of the line, from 1 per logger and its children: #42
+	Lpackage                      // the package of the caller: pkg=cache
+	Lentryid                      // a random identifier of the line, see SetEntryIDFunc: id=3f9a0c12d4e7
 	LstdFlags     = Ldate | Ltime // initial values for the standard logger
 )
 
@@ -48,13 +63,69 @@ const (
 	WARNING
 	ERROR
 	FATAL
+	NOLEVEL = -1 // level of the entries written by Output and the Print, Fatal and Panic families
 )
 
 var (
-	gStd     = newEx(os.Stderr, "", LstdFlags)                     //global handle
-	levelStr = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"} //Log level str
+	gStd       = newEx(os.Stderr, "", LstdFlags)                     //global handle
+	levelStr   = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"} //Log level str
+	pid        = os.Getpid()                                         //cached for Lpid
+	exit       = os.Exit                                             //terminates the process after a fatal log, stubbed by tests
+	now        = time.Now                                            //the clock of the entries, stubbed by tests
+	isTerminal = terminal                                            //decides SetAutoColor, stubbed by tests
+	rename     = os.Rename                                           //moves the archives, stubbed by tests
 )
 
+/*ErrNotFile is returned by the operations that need a file backed logger.*/
+var ErrNotFile = errors.New("glog: the logger does not write to a file")
+
+/*ErrNotBuffer is returned by FlushString for a logger which does not write to a bytes.Buffer.*/
+var ErrNotBuffer = errors.New("glog: the logger does not write to a bytes.Buffer")
+
+/*ErrTimeout is returned by OutputTimeout when the entry was not written in time.*/
+var ErrTimeout = errors.New("glog: the write did not complete in time")
+
+/*
+settings are the tunables of a Logger, as opposed to the state of its output:
+a child from With starts with a copy of them, sharing the samplers and the
+rate limit, and Reset restores them as a unit. l.mu protects them.
+*/
+type settings struct {
+	prefix        string                       // prefix to write at beginning of each line
+	flag          int                          // properties
+	panicValue    func(msg string) interface{} // builds the value passed to panic(), nil means the message itself
+	headerSep     string                       // written after the date and after the time
+	formatter     Formatter                    // renders the entries, nil means the text format
+	handler       Handler                      // receives the entries instead of formatter and out
+	fields        []Field                      // attached to every entry, see With
+	skipEmpty     bool                         // drop the entries whose message is blank and without fields
+	levelPad      int                          // width of the level name, see TextFormatter.LevelPad
+	autoColor     bool                         // color the level tokens when out is a terminal
+	color         bool                         // autoColor's decision for the current out
+	filter        func(e Entry) bool           // drops the entries it returns false for, see SetFilter
+	replaceUTF8   bool                         // replace the invalid UTF-8 of the messages
+	errOut        io.Writer                    // receives the logger's own diagnostics, nil means os.Stderr
+	orderedTime   bool                         // take the time of the entries with the lock held
+	headerFrames  int                          // caller frames in the header, 0 and 1 mean the caller only
+	lineSuffix    func(e Entry) string         // written at the end of each line, see SetLineSuffix
+	maxFields     int                          // fields rendered per entry, 0 renders them all
+	location      *time.Location               // zone of the header time, nil means the local time zone
+	headerPerLine bool                         // repeat the header on each line of a multi-line message
+	renderNil     bool                         // replace the nil field values with nilRender
+	nilRender     string                       // see SetNilRender
+	resolution    time.Duration                // the header time is truncated to it, see SetTimeResolution
+	compactLevel  bool                         // single character level tokens, see SetCompactLevel
+	beatLevel     int                          // level of the heartbeat lines less INFO, so zero is INFO
+	bare          bool                         // write the messages without header, see SetBare
+	prefixFunc    func() string                // computes the prefix of each line, see SetPrefixFunc
+	maxMessage    int                          // bytes of a message, 0 does not truncate them
+	truncSummary  bool                         // follow the truncated lines with a summary, see SetTruncationSummary
+	entryIDFunc   func() string                // generates the identifiers of Lentryid, nil for the random ones
+	hooks         []func(Entry)                // receive every entry written, see AddHook
+	burst         *burstSampler                // drops the repeats of the messages, see SetBurstSampler
+	rate          *rateLimiter                 // diverts the entries over the rate, see SetRateLimit
+}
+
 /*
 A Logger represents an active logging object that generates lines of
 output to an io.Writer. Each logging operation makes a single call to
@@ -63,16 +134,49 @@ multiple goroutines; it guarantees to serialize access to the Writer.
 */
 type Logger struct {
 	mu               sync.Mutex // ensures atomic writes; protects the following fields
-	prefix           string     // prefix to write at beginning of each line
-	flag             int        // properties
+	settings                    // the tunables a child of With starts with, see settings
 	out              io.Writer  // destination for output
 	buf              []byte     // for accumulating text to write
 	filename         string     // log file name
 	fileHandle       *os.File   // file handle
 	writtenSize      uint64     // already written the size
-	splitFileSize    uint64     // the logfile limit size
+	splitFileSize    uint64     // the logfile limit size, 0 disables the rotation
 	splitRotateIndex int        // current rotate index
 	totalRotateSplit int        // total rotate writes
+
+	lineCallbacks []*lineCallback         // receive every successfully written line
+	followSymlink bool                    // rotate the target of a symlinked filename
+	level         int32                   // minimum level written, accessed atomically
+	async         *asyncQueue             // queue to the writer goroutine, nil writes synchronously
+	archive       func(path string) error // ships the archived files, see SetArchiveHandler
+	archives      sync.WaitGroup          // the archive handlers in progress
+	shipping      map[string]bool         // the archives handed to the archive handler and not deleted yet
+	closed        bool                    // Close is waiting for the archive handlers, the rotation starts no more
+	progressMu    sync.Mutex              // protects progress, apart from mu as Progress logs while holding it
+	progress      map[string]progressMark // the previous call of Progress per key
+	printLevel    int32                   // level of the Print family less NOLEVEL, so zero is NOLEVEL, accessed atomically
+	sampling      atomic.Value            // holds the *levelSampling, see SetLevelSampling
+	writtenLines  int                     // lines written to the active file
+	lineRotate    int                     // rotate after so many lines, 0 rotates on the size only
+	archiveDir    string                  // where the archives go, "" means next to the active file
+	archiveOwned  bool                    // archiveDir was created by SetArchiveDir
+	archiveGrace  time.Duration           // see SetRemoveEmptyArchiveDir, 0 keeps the directory
+	seq           uint64                  // sequence number of the last line, see Lseq, accessed atomically
+	seqOwner      *Logger                 // the logger numbering the lines, the root of a child from With, nil for l itself
+	rotations     uint64                  // rotations so far, see Stats
+	rotateErrors  uint64                  // rotations which could not archive the file or reopen it
+	lastRotation  time.Duration           // how long the last rotation took
+	lastArchive   time.Duration           // how long the archive handler took on the last archive
+	heartbeat     *heartbeat              // the running heartbeat, see StartHeartbeat
+	cooldown      atomic.Value            // holds the *errorCooldown, see SetErrorCooldown
+	summarizing   bool                    // a summary line is being written
+	lastErr       error                   // the last internal error or failed write, see LastError
+	lastErrTime   time.Time               // when lastErr occurred
+	batching      bool                    // OutputBatch is writing, the rotation waits for its end
+	abandoned     int32                   // OutputTimeout calls given up while waiting for the lock, accessed atomically
+	renameRetries int                     // retries of a failed rename of the rotation, see SetRenameRetry
+	renameDelay   time.Duration           // wait before the first retry, doubled on each one
+	copyTruncate  bool                    // copy and truncate the file when the rename fails, see SetCopyTruncate
 }
 
 /*
@@ -80,7 +184,8 @@ New creates a new Logger. The out variable sets the
 destination to which log data will be written.
 The prefix appears at the beginning of each generated log line.
 The flag argument defines the logging properties.
-The splitSize argument defines the logfile size, the unit is MB	  (1*1024*1024)Byte
+The splitSize argument defines the logfile size, the unit is MB	  (1*1024*1024)Byte,
+a splitSize of zero or less disables the rotation and lets the file grow unbounded
 The splitCount argument defines the total rotate split counts
 */
 
@@ -89,290 +194,1760 @@ func New(filename string, prefix string, flag int) *Logger {
 }
 
 func NewEx(filename string, prefix string, flag int, splitSize int, splitCount int) *Logger {
+	if splitSize < 0 {
+		splitSize = 0
+	}
+	return NewExBytes(filename, prefix, flag, uint64(splitSize)*1024*1024, splitCount)
+}
+
+/*
+NewExBytes is NewEx with the logfile size given in bytes rather than MB,
+a splitBytes of zero disables the rotation.
+*/
+func NewExBytes(filename string, prefix string, flag int, splitBytes uint64, splitCount int) *Logger {
 	openLogFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return nil
 	}
-	return &Logger{filename: filename, prefix: prefix, flag: flag, splitFileSize: uint64(splitSize * 1024 * 1024), totalRotateSplit: splitCount, fileHandle: openLogFile, out: openLogFile, writtenSize: 0}
+	return &Logger{settings: settings{prefix: prefix, flag: flag, headerSep: " "}, filename: filename, splitFileSize: splitBytes, totalRotateSplit: splitCount, fileHandle: openLogFile, out: openLogFile, writtenSize: 0,
+		renameRetries: defaultRenameRetries, renameDelay: defaultRenameDelay, copyTruncate: defaultCopyTruncate}
+}
+
+/*
+NewBare creates a Logger writing the bare messages to filename, with neither
+prefix, header nor level token, for the consumers adding their own metadata,
+see SetBare. It returns nil if the file cannot be opened, as New does.
+*/
+func NewBare(filename string) *Logger {
+	l := New(filename, "", 0)
+	if l != nil {
+		l.bare = true
+	}
+	return l
+}
+
+/*
+NewFromFd creates a Logger writing to the inherited file descriptor fd, e.g. a
+pipe set up by a supervisor. The rotation is disabled as fd need not be a
+regular file. It returns nil if fd is not a valid descriptor.
+*/
+func NewFromFd(fd uintptr, prefix string, flag int) *Logger {
+	f := os.NewFile(fd, "fd"+strconv.FormatUint(uint64(fd), 10))
+	if f == nil {
+		return nil
+	}
+	return &Logger{settings: settings{prefix: prefix, flag: flag, headerSep: " "}, filename: "", splitFileSize: 0, totalRotateSplit: 0, fileHandle: f, out: f, writtenSize: 0}
 }
 
 func newEx(out io.Writer, prefix string, flag int) *Logger {
-	return &Logger{filename: "", prefix: prefix, flag: flag, splitFileSize: uint64(SPLIT_FILE_SIZE * 1024 * 1024), totalRotateSplit: TOTAL_ROTATE_SPLIT, fileHandle: nil, out: out, writtenSize: 0}
+	return &Logger{settings: settings{prefix: prefix, flag: flag, headerSep: " "}, filename: "", splitFileSize: uint64(SPLIT_FILE_SIZE * 1024 * 1024), totalRotateSplit: TOTAL_ROTATE_SPLIT, fileHandle: nil, out: out, writtenSize: 0}
 }
 
-/*rotate the log file*/
+/*
+RotateNotifier is implemented by outputs that want to know about rotations,
+e.g. to upload the archived chunk. After each rotation the logger calls
+OnRotate on its output, if it implements the interface, with the path the
+active file was archived to; it is not called when the file could not be
+archived, the active file then carrying on.
+*/
+type RotateNotifier interface {
+	OnRotate(oldPath string)
+}
+
+/*rotate the log file, the written size of the new file starts over at zero*/
 func (l *Logger) rotate() (err error) {
+	start := time.Now()
+	archived := true
+	defer func() {
+		if err != nil || !archived {
+			l.rotateErrors++
+			return
+		}
+		l.rotations++
+		l.lastRotation = time.Since(start)
+	}()
+	oldHandle := l.fileHandle
 	_ = l.fileHandle.Close()
-	_ = os.Rename(l.filename, fmt.Sprintf("%s.%d", l.filename, l.splitRotateIndex))
+	path := l.filename
+	if l.followSymlink {
+		if target, err := filepath.EvalSymlinks(l.filename); err == nil {
+			path = target
+		}
+	}
+	oldPath := fmt.Sprintf("%s.%d", path, l.splitRotateIndex)
+	if l.archiveDir != "" {
+		oldPath = filepath.Join(l.archiveDir, filepath.Base(oldPath))
+		if l.archiveGrace > 0 {
+			_ = os.MkdirAll(l.archiveDir, 0755) // it may have been removed as empty
+		}
+	}
+	if l.shipping[oldPath] {
+		oldPath = l.freeArchivePath(oldPath)
+	}
+	if err := l.archiveFile(path, oldPath); err != nil {
+		archived = false
+		l.internalError("cannot archive the log file: %v", err)
+	} else if l.archive != nil && !l.closed {
+		if l.shipping == nil {
+			l.shipping = make(map[string]bool)
+		}
+		l.shipping[oldPath] = true
+		l.archives.Add(1)
+		go l.runArchive(l.archive, oldPath)
+	}
+	l.writtenSize = 0
+	l.writtenLines = 0
 	l.fileHandle, err = os.OpenFile(l.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
-	l.out = l.fileHandle
+	if l.out == io.Writer(oldHandle) {
+		l.out = l.fileHandle
+	}
 	l.splitRotateIndex++
 	if l.splitRotateIndex > l.totalRotateSplit {
 		l.splitRotateIndex = 0
 	}
+	if notifier, ok := l.out.(RotateNotifier); ok && archived {
+		notifier.OnRotate(oldPath)
+	}
 	return err
 }
 
-/*Set the file handle*/
-func (l *Logger) setFileHandle(handle *os.File) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.fileHandle = handle
+/*
+SetArchiveDir makes the rotation move the archives into dir, created if
+missing, rather than leaving them next to the active file, e.g. to keep
+them on cheaper storage. dir may be on another file system, the archives
+are then copied over and removed. An empty dir restores the default.
+*/
+func (l *Logger) SetArchiveDir(dir string) error {
+	created := false
+	if dir != "" {
+		_, err := os.Stat(dir)
+		created = os.IsNotExist(err)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if dir != l.archiveDir {
+		l.archiveOwned = created
+	}
+	l.archiveDir = dir
+	return nil
+}
+
+/*
+SetRemoveEmptyArchiveDir removes the directory of SetArchiveDir once the
+archive handler has shipped and deleted all the archives in it and it has
+stayed empty for grace, the next rotation creating it again. Only a directory
+that SetArchiveDir had to create is removed, never one that existed before,
+which may be shared, nor one holding anything else. A grace of 0 or less
+keeps the directory.
+*/
+func (l *Logger) SetRemoveEmptyArchiveDir(grace time.Duration) {
+	if grace < 0 {
+		grace = 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.archiveGrace = grace
+}
+
+/*removeEmptyArchiveDir removes dir if it still is the empty archive directory of l and may be removed.*/
+func (l *Logger) removeEmptyArchiveDir(dir string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.archiveGrace <= 0 || !l.archiveOwned || dir != l.archiveDir {
+		return
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 {
+		return
+	}
+	if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+		l.internalError("cannot remove the archive directory %s: %v", dir, err)
+	}
+}
+
+/*
+SetRenameRetry sets how the rotation copes with a failed rename of the
+active file, as happens on Windows while another process holds the archive
+or the file open: the rename is retried up to retries times, waiting delay
+then twice as long each time, with the logger locked. On Windows it is
+retried 3 times from 10ms by default, elsewhere not at all. A retries of 0
+or less does not retry.
+*/
+func (l *Logger) SetRenameRetry(retries int, delay time.Duration) {
+	if retries < 0 {
+		retries = 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.renameRetries, l.renameDelay = retries, delay
+}
+
+/*
+SetCopyTruncate sets whether the rotation, once the rename of the active
+file has failed for good, falls back to copying the file to the archive and
+truncating it, which works on a file held open elsewhere; the fallback is
+reported to the internal error writer. It is on by default on Windows only.
+Without it the rotation is reported as failed and the lines go on to the
+same file.
+*/
+func (l *Logger) SetCopyTruncate(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.copyTruncate = on
+}
+
+/*archiveFile moves the active file to oldPath for the rotation, retrying and falling back as set, l.mu must be held.*/
+func (l *Logger) archiveFile(path, oldPath string) error {
+	err := moveFile(path, oldPath)
+	delay := l.renameDelay
+	for retry := 0; err != nil && retry < l.renameRetries; retry++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = moveFile(path, oldPath)
+	}
+	if err == nil || !l.copyTruncate {
+		return err
+	}
+	if cerr := copyFile(path, oldPath); cerr != nil {
+		return fmt.Errorf("%v, then cannot copy it: %v", err, cerr)
+	}
+	if terr := os.Truncate(path, 0); terr != nil {
+		return fmt.Errorf("%v, then cannot truncate it: %v", err, terr)
+	}
+	l.internalError("cannot rename %s, copied and truncated it instead: %v", path, err)
+	return nil
+}
+
+/*moveFile renames src to dst, copying it over when they are on different file systems.*/
+func moveFile(src, dst string) error {
+	err := rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+/*copyFile copies src over dst, removing dst again when the copy fails.*/
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+/*
+SetArchiveHandler registers fn to ship each archived log file, e.g. to object
+storage. fn runs in a goroutine of its own after the rotation, with the path
+the file was archived to; when it returns nil the file is deleted, when it
+returns an error the file is kept and the error reported. A rotation coming
+back round to the path of a file still being shipped archives to a path of
+its own instead, the path of the round with a counter appended, e.g.
+app.log.0.1, so that the handler deletes only the file it was given. Close
+waits for the handlers in progress.
+*/
+func (l *Logger) SetArchiveHandler(fn func(path string) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.archive = fn
+}
+
+/*freeArchivePath returns oldPath with the first counter appended that names neither a file being shipped nor an existing one, l.mu must be held.*/
+func (l *Logger) freeArchivePath(oldPath string) string {
+	for i := 1; ; i++ {
+		path := fmt.Sprintf("%s.%d", oldPath, i)
+		if _, err := os.Lstat(path); !l.shipping[path] && os.IsNotExist(err) {
+			return path
+		}
+	}
+}
+
+/*runArchive hands path to the archive handler fn and deletes it once shipped.*/
+func (l *Logger) runArchive(fn func(path string) error, path string) {
+	defer l.archives.Done()
+	start := time.Now()
+	err := fn(path)
+	took := time.Since(start)
+	if err == nil {
+		err = os.Remove(path)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.shipping, path)
+	l.lastArchive = took
+	if err != nil {
+		l.internalError("cannot ship %s: %v", path, err)
+		return
+	}
+	if dir := filepath.Dir(path); l.archiveGrace > 0 && l.archiveOwned && dir == l.archiveDir {
+		time.AfterFunc(l.archiveGrace, func() { l.removeEmptyArchiveDir(dir) })
+	}
+}
+
+/*
+Close stops the heartbeat, writes the entries queued in async mode, waits for
+the archive handlers in progress and closes the log file. The logger must not
+be used afterwards; the files a rotation archives from then on are not handed
+to the archive handler.
+*/
+func (l *Logger) Close() error {
+	l.StopHeartbeat()
+	l.mu.Lock()
+	q := l.async
+	l.async = nil
+	l.mu.Unlock()
+	if q != nil {
+		q.close()
+	}
+	/*Mark it under the lock, so that no rotation adds to the archives being waited for.*/
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.archives.Wait()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fileHandle == nil {
+		return nil
+	}
+	return l.fileHandle.Close()
+}
+
+/*
+OnShutdown returns a function tearing the logger down for a shutdown manager:
+it writes the pending entries as Barrier does, syncs the log file to disk and
+closes the logger. The teardown runs once however often the function is called, the
+later calls returning the error of the first one.
+*/
+func (l *Logger) OnShutdown() func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() { err = l.shutdown() })
+		return err
+	}
+}
+
+func OnShutdown() func() error {
+	return gStd.OnShutdown()
+}
+
+/*shutdown is the teardown of OnShutdown.*/
+func (l *Logger) shutdown() error {
+	errs := []error{l.Barrier()}
+	l.mu.Lock()
+	if l.filename != "" {
+		errs = append(errs, l.fileHandle.Sync())
+	}
+	l.mu.Unlock()
+	errs = append(errs, l.Close())
+	return errors.Join(errs...)
+}
+
+/*
+Barrier blocks until the entries logged before the call are written: those
+queued in async mode, and the pending ones of a Handler batching its entries
+which has a Flush() error method, like DBSink, or else of an output which has
+one, like BufferedWriter. The logger stays open, so tests can check the output
+of an async logger at a known point. It returns the error of the Flush.
+*/
+func (l *Logger) Barrier() error {
+	l.mu.Lock()
+	q := l.async
+	l.mu.Unlock()
+	if q != nil {
+		q.flush()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.handler != nil {
+		if f, ok := l.handler.(interface{ Flush() error }); ok {
+			return f.Flush()
+		}
+		return nil
+	}
+	if f, ok := l.out.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func Barrier() error {
+	return gStd.Barrier()
+}
+
+/*
+Reset returns the settings of the logger to those of New: the LstdFlags flags,
+no prefix, the DEBUG level and the default split size and count, dropping the
+line callbacks, the hooks, the filter, the formatter, the handler, the fields,
+the samplers, the rate limit and the error cooldown, stopping the heartbeat and
+writing synchronously again. The open file, the bytes and lines already
+written to it and the rotation index are kept, and a file backed logger
+writes to its file again; a logger without a file keeps its output.
+*/
+func (l *Logger) Reset() {
+	l.StopHeartbeat()
+	l.mu.Lock()
+	q := l.async
+	l.async = nil
+	l.mu.Unlock()
+	if q != nil {
+		q.close()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings = settings{flag: LstdFlags, headerSep: " "}
+	if l.fileHandle != nil {
+		l.out = l.fileHandle
+	}
+	l.splitFileSize = uint64(SPLIT_FILE_SIZE * 1024 * 1024)
+	l.totalRotateSplit = TOTAL_ROTATE_SPLIT
+	l.lineCallbacks = nil
+	l.followSymlink = false
+	atomic.StoreInt32(&l.level, DEBUG)
+	l.archive = nil
+	atomic.StoreInt32(&l.printLevel, 0)
+	l.sampling.Store((*levelSampling)(nil))
+	l.lineRotate = 0
+	l.archiveDir = ""
+	l.archiveOwned = false
+	l.archiveGrace = 0
+	l.renameRetries, l.renameDelay = defaultRenameRetries, defaultRenameDelay
+	l.copyTruncate = defaultCopyTruncate
+	l.cooldown.Store((*errorCooldown)(nil))
+	l.progressMu.Lock()
+	l.progress = nil
+	l.progressMu.Unlock()
+}
+
+func Reset() {
+	gStd.Reset()
+}
+
+/*
+Rotate archives the active log file as the next rotation would and
+reopens a fresh file, regardless of how much has been written to it.
+*/
+func (l *Logger) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.filename == "" {
+		return ErrNotFile
+	}
+	return l.rotate()
+}
+
+/*
+SetFilename moves the logger over to the file at newPath, e.g. a date stamped
+name, once the entries queued in async mode are written: the new file is
+opened for appending and the old one synced and closed, with the lock held so
+that no line is split between them. The bytes already in the new file count
+against the split size and the rotation index starts over. Should newPath not
+open, the logger keeps its file. It returns ErrNotFile for a logger without
+a file.
+*/
+func (l *Logger) SetFilename(newPath string) error {
+	l.mu.Lock()
+	q := l.async
+	l.mu.Unlock()
+	if q != nil {
+		q.flush()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.filename == "" {
+		return ErrNotFile
+	}
+	f, err := os.OpenFile(newPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	oldHandle := l.fileHandle
+	if err := oldHandle.Sync(); err != nil {
+		l.internalError("cannot sync %s: %v", l.filename, err)
+	}
+	_ = oldHandle.Close()
+	l.fileHandle = f
+	if l.out == io.Writer(oldHandle) {
+		l.out = f
+	}
+	l.filename = newPath
+	l.writtenSize = uint64(info.Size())
+	l.writtenLines = 0
+	l.splitRotateIndex = 0
+	return nil
+}
+
+func SetFilename(newPath string) error {
+	return gStd.SetFilename(newPath)
+}
+
+/*
+BytesUntilRotate returns how many bytes can still be written to the active
+file before it rotates, math.MaxUint64 when the rotation is disabled or the
+logger does not write to a file.
+*/
+func (l *Logger) BytesUntilRotate() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.splitFileSize == 0 || l.filename == "" {
+		return math.MaxUint64
+	}
+	if l.writtenSize >= l.splitFileSize {
+		return 0
+	}
+	return l.splitFileSize - l.writtenSize
+}
+
+/*
+SetSplitBytes sets the logfile size in bytes from which the file rotates,
+0 disables the rotation. The bytes already written to the active file count
+against the new size.
+*/
+func (l *Logger) SetSplitBytes(n uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.splitFileSize = n
+}
+
+/*
+SetLineRotate makes the file rotate once n lines, one per entry, were written
+to it, whatever their size, as well as on the split size. An n of 0 rotates
+on the size only.
+*/
+func (l *Logger) SetLineRotate(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lineRotate = n
+}
+
+/*
+SetMaxFields caps the fields of each entry, those of With included, to n so
+that a runaway chain of With cannot produce enormous records. The fields past
+the first n are dropped and replaced by a "_fields_truncated" field holding
+how many were. The global fields are not counted. An n of 0 removes the cap.
+*/
+func (l *Logger) SetMaxFields(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxFields = n
+}
+
+func SetMaxFields(n int) {
+	gStd.SetMaxFields(n)
+}
+
+/*
+SetMaxMessageSize truncates the messages longer than n bytes to their first
+n bytes, on a character boundary, so that a runaway message cannot produce
+an enormous line. See SetTruncationSummary to keep track of what was cut. An
+n of 0 removes the cap.
+*/
+func (l *Logger) SetMaxMessageSize(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxMessage = n
+}
+
+func SetMaxMessageSize(n int) {
+	gStd.SetMaxMessageSize(n)
+}
+
+/*
+SetTruncationSummary makes the logger follow each line whose message was
+truncated by SetMaxMessageSize with a line at INFO giving the size of the
+whole message and its first 64 bytes, so that nothing is cut silently:
+
+	[INFO]: truncated message: 52428800 bytes, starts with "GET /upload?id=42 ..."
+
+The summary lines are not truncated themselves.
+*/
+func (l *Logger) SetTruncationSummary(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.truncSummary = on
+}
+
+func SetTruncationSummary(on bool) {
+	gStd.SetTruncationSummary(on)
+}
+
+/*truncationPreview is how much of a truncated message its summary line shows.*/
+const truncationPreview = 64
+
+/*summarize writes the summary line of the entry e whose whole message of size bytes was truncated, l.mu must be held.*/
+func (l *Logger) summarize(e Entry, size int, whole string) {
+	l.summarizing = true
+	defer func() { l.summarizing = false }()
+	msg := fmt.Sprintf("truncated message: %d bytes, starts with %q", size, truncateString(whole, truncationPreview))
+	_ = l.outputLocked(Entry{Time: e.Time, Level: INFO, Prefix: e.Prefix, Message: msg}, nil, nil)
+}
+
+/*truncateString returns the first n bytes of s at most, without splitting a character.*/
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+/*
+SetNilRender sets how the nil field values, nil errors and pointers included,
+are written, e.g. "" or "null", in place of <nil> in the text format and null
+in JSON, where s is written as a string. It applies to every Formatter and
+Handler as the values are replaced before the entry reaches them.
+*/
+func (l *Logger) SetNilRender(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.renderNil = true
+	l.nilRender = s
+}
+
+func SetNilRender(s string) {
+	gStd.SetNilRender(s)
+}
+
+/*
+SetFollowSymlink sets how the rotation treats a filename that is a symlink.
+By default the link itself is archived, it keeps pointing at its target,
+and a regular file replaces it. With follow set the target is archived
+next to itself and recreated, so the link keeps pointing at the active file.
+*/
+func (l *Logger) SetFollowSymlink(follow bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.followSymlink = follow
+}
+
+/*Set the file handle*/
+func (l *Logger) setFileHandle(handle *os.File) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fileHandle = handle
+}
+
+/*
+SetOutput sets the output destination for the logger.
+A file backed logger keeps rotating its file, a custom output survives the
+rotations and is notified of them if it implements RotateNotifier.
+*/
+func (l *Logger) SetOutput(w io.Writer) {
+	l.SwapOutput(w)
+}
+
+func SetOutput(w io.Writer) {
+	gStd.SetOutput(w)
+}
+
+/*SwapOutput sets the output destination like SetOutput and returns the previous one.*/
+func (l *Logger) SwapOutput(w io.Writer) io.Writer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.out
+	l.out = w
+	l.color = l.autoColor && isTerminal(w)
+	return old
+}
+
+func SwapOutput(w io.Writer) io.Writer {
+	return gStd.SwapOutput(w)
+}
+
+/*
+SetFilter drops the entries for which keep returns false before they are
+formatted, e.g. the lines of the health checks. keep is called with the
+logger locked, it must be quick and must not log through the logger.
+A nil keep writes all the entries again.
+*/
+func (l *Logger) SetFilter(keep func(e Entry) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.filter = keep
+}
+
+func SetFilter(keep func(e Entry) bool) {
+	gStd.SetFilter(keep)
+}
+
+/*
+SetReplaceInvalidUTF8 makes the logger replace each run of bytes of the
+messages which is not valid UTF-8 with the replacement character U+FFFD,
+so that raw binary data cannot corrupt the JSON sinks or the log viewers.
+*/
+func (l *Logger) SetReplaceInvalidUTF8(replace bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.replaceUTF8 = replace
+}
+
+func SetReplaceInvalidUTF8(replace bool) {
+	gStd.SetReplaceInvalidUTF8(replace)
+}
+
+/*
+SetInternalErrorWriter sets where the logger reports its own problems, like a
+failed rotation, so that they do not end up in the stream that is failing.
+A nil w restores the default, os.Stderr.
+*/
+func (l *Logger) SetInternalErrorWriter(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errOut = w
+}
+
+func SetInternalErrorWriter(w io.Writer) {
+	gStd.SetInternalErrorWriter(w)
+}
+
+/*internalError writes a diagnostic line to the internal error writer and keeps it for LastError, l.mu must be held.*/
+func (l *Logger) internalError(format string, v ...interface{}) {
+	l.lastErrTime, l.lastErr = now(), fmt.Errorf(format, v...)
+	w := l.errOut
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "glog: "+format+"\n", v...)
+}
+
+/*
+LastError returns the time of the most recent problem of the logger, then
+the problem itself: a diagnostic sent to the internal error writer, like a
+failed rotation, or a failed write to the output. The error comes last, as
+Go results do. A successful write clears both, so that a nil error means the
+logger is writing fine again, not that it never failed.
+*/
+func (l *Logger) LastError() (time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastErrTime, l.lastErr
+}
+
+func LastError() (time.Time, error) {
+	return gStd.LastError()
+}
+
+/*
+SetOrderedTimestamps sets when the time of an entry is taken. By default it
+is taken first thing, before waiting for the lock, so that it is as close to
+the call as possible, and concurrent callers may write their lines out of
+timestamp order. With ordered set it is taken once the lock is held, and the
+timestamps of the lines never decrease. In async mode the lines are
+ordered as queued, which still may differ from the timestamp order.
+*/
+func (l *Logger) SetOrderedTimestamps(ordered bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.orderedTime = ordered
+}
+
+func SetOrderedTimestamps(ordered bool) {
+	gStd.SetOrderedTimestamps(ordered)
+}
+
+/*
+SetHeaderFrames makes the header show the last n frames of the call stack
+rather than the caller alone, innermost first as in d.go:23<-c.go:40<-b.go:12,
+when Llongfile or Lshortfile is specified. The default n is 1.
+*/
+func (l *Logger) SetHeaderFrames(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.headerFrames = n
+}
+
+func SetHeaderFrames(n int) {
+	gStd.SetHeaderFrames(n)
+}
+
+/*
+SetLineSuffix appends the text suffix returns for each entry to its line,
+right before the newline, e.g. a checksum or the ID of the trace span. An
+empty suffix leaves the line as is, a nil suffix turns the feature off.
+It applies to the formatted lines, not to the entries given to a Handler.
+*/
+func (l *Logger) SetLineSuffix(suffix func(e Entry) string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lineSuffix = suffix
+}
+
+func SetLineSuffix(suffix func(e Entry) string) {
+	gStd.SetLineSuffix(suffix)
+}
+
+/*
+SetAutoColor colors the level tokens of the text format with ANSI escapes
+while the output is a terminal. The decision is made again whenever the output
+changes, so that a logger redirected to a file writes no escapes into it; the
+children of With follow the output of the logger they write through.
+*/
+func (l *Logger) SetAutoColor(auto bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.autoColor = auto
+	l.color = auto && isTerminal(l.out)
+}
+
+func SetAutoColor(auto bool) {
+	gStd.SetAutoColor(auto)
+}
+
+/*terminal reports whether w is a character device, such as a terminal.*/
+func terminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+/*
+AddLineCallback registers fn to receive every formatted line, newline included,
+after it has been written successfully, e.g. to stream it to a live viewer.
+Each call gets its own copy of the line, so fn may keep it or hand it to
+another goroutine. Callbacks run in write order while the logger's lock is
+held: they must return quickly, so push the line onto a buffered channel
+rather than doing network I/O inline, and must not log to the same logger.
+*/
+func (l *Logger) AddLineCallback(fn func(line []byte)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lineCallbacks = append(l.lineCallbacks, &lineCallback{fn: fn})
+}
+
+func AddLineCallback(fn func(line []byte)) {
+	gStd.AddLineCallback(fn)
+}
+
+/*lineCallback wraps a line callback so that it can be told apart for removal.*/
+type lineCallback struct {
+	fn func(line []byte)
+}
+
+/*removeLineCallback unregisters c, l.mu must be held.*/
+func (l *Logger) removeLineCallback(c *lineCallback) {
+	callbacks := make([]*lineCallback, 0, len(l.lineCallbacks))
+	for _, other := range l.lineCallbacks {
+		if other != c {
+			callbacks = append(callbacks, other)
+		}
+	}
+	l.lineCallbacks = callbacks
+}
+
+/*Cheap integer to fixed-width decimal ASCII. Give a negative width to avoid zero-padding.*/
+func itoa(buf *[]byte, i int, wid int) {
+	/*Assemble decimal in reverse order.*/
+	var b [20]byte
+	bp := len(b) - 1
+	for i >= 10 || wid > 1 {
+		wid--
+		q := i / 10
+		b[bp] = byte('0' + i - q*10)
+		bp--
+		i = q
+	}
+	// i < 10
+	b[bp] = byte('0' + i)
+	*buf = append(*buf, b[bp:]...)
+}
+
+/*
+Output writes the output for a logging event. The string s contains
+the text to print after the prefix specified by the flags of the
+Logger. A newline is appended if the last character of s is not
+already a newline, and a final \r\n is written as \n. Calldepth is
+used to recover the PC and is provided for generality, although at
+the moment on all pre-defined paths it will be 2.
+*/
+func (l *Logger) Output(calldepth int, s string) error {
+	return l.output(calldepth+1, NOLEVEL, nil, s, nil) // +1 for this frame.
+}
+func Output(calldepth int, s string) error {
+	return gStd.output(calldepth+1, NOLEVEL, nil, s, nil) // +1 for this frame.
+}
+
+/*
+OutputBytes is like Output but takes the text as a byte slice, which is
+appended to the line as is, sparing callers that already hold bytes the
+conversion to a string. The logger does not retain p.
+*/
+func (l *Logger) OutputBytes(calldepth int, p []byte) error {
+	return l.output(calldepth+1, NOLEVEL, nil, "", p) // +1 for this frame.
+}
+func OutputBytes(calldepth int, p []byte) error {
+	return gStd.output(calldepth+1, NOLEVEL, nil, "", p) // +1 for this frame.
+}
+
+/*noCaller is the calldepth of output for the entries logged without their caller, see InfoNoCaller.*/
+const noCaller = -1
+
+/*
+output logs an entry of the given level and fields whose text is s followed by p.
+With the default text format the bytes of p are copied to the line as is.
+
+The lock is released while the caller info is resolved. That gap is safe
+because runtime.Caller touches no Logger state: l.buf, l.writtenSize and
+the rotation are only read and written after the lock is taken again, within
+a single critical section that formats, writes, accounts and rotates. Never
+touch these fields between the Unlock and the Lock below.
+*/
+func (l *Logger) output(calldepth int, level int, fields []Field, s string, p []byte) error {
+	t := now() // get this early.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := Entry{Level: level, Message: s, Fields: fields}
+	if calldepth != noCaller && l.flag&(Lshortfile|Llongfile|Lmethodonly|Lpackage) != 0 {
+		frames := l.headerFrames
+		flag := l.flag
+		/*Release lock while getting caller info - it's expensive.*/
+		l.mu.Unlock()
+		setCaller(&e, calldepth+1, flag, frames) // +1 for this frame.
+		l.mu.Lock()
+	}
+	if l.orderedTime {
+		t = now()
+	}
+	e.Time = t
+	return l.outputLocked(e, p, nil)
+}
+
+/*
+setCaller fills in the caller info of e that flag asks for, the file and
+line with the frames of SetHeaderFrames and the function, for the caller at
+calldepth, counted as by runtime.Caller from the caller of setCaller.
+*/
+func setCaller(e *Entry, calldepth int, flag int, frames int) {
+	if flag&(Lshortfile|Llongfile) != 0 {
+		var ok bool
+		_, e.File, e.Line, ok = runtime.Caller(calldepth)
+		if !ok {
+			e.File = "???"
+			e.Line = 0
+		}
+		if frames > 1 {
+			e.Callers = callersFrom(calldepth+2, frames-1) // one deeper for callersFrom itself
+		}
+	}
+	if flag&(Lmethodonly|Lpackage) != 0 {
+		e.Func, e.Package = callerFunc(calldepth+1, flag) // one deeper for callerFunc itself
+	}
+}
+
+/*
+callerFunc returns the name of the function at calldepth, counted as by
+runtime.Caller, without its package if flag has Lmethodonly, and the name
+of its package if flag has Lpackage.
+*/
+func callerFunc(calldepth int, flag int) (fn, pkg string) {
+	var pcs [1]uintptr
+	var name string
+	if runtime.Callers(calldepth+1, pcs[:]) > 0 {
+		frame, _ := runtime.CallersFrames(pcs[:]).Next()
+		name = frame.Function
+	}
+	return funcNames(name, flag)
+}
+
+/*funcNames splits the fully qualified function name into the parts flag asks for, see callerFunc.*/
+func funcNames(name string, flag int) (fn, pkg string) {
+	if flag&Lmethodonly != 0 {
+		fn = methodName(name)
+	}
+	if flag&Lpackage != 0 {
+		pkg = packageName(name)
+	}
+	return fn, pkg
+}
+
+/*
+packageName returns the name of the package of a fully qualified function
+name, the last element of its import path: "cache" for
+"github.com/a/cache.(*LRU).Get.func1". The names of the main package start
+with "main.".
+*/
+func packageName(fn string) string {
+	if fn == "" {
+		return "???"
+	}
+	fn = fn[strings.LastIndexByte(fn, '/')+1:]
+	if i := strings.IndexByte(fn, '.'); i >= 0 {
+		fn = fn[:i]
+	}
+	return fn
+}
+
+/*
+methodName strips a fully qualified function name down to the name of the
+function or method, "github.com/a/b.(*Server).handle" to "handle". The
+closures are named after the function declaring them.
+*/
+func methodName(fn string) string {
+	if fn == "" {
+		return "???"
+	}
+	fn = fn[strings.LastIndexByte(fn, '/')+1:]
+	if i := strings.IndexByte(fn, '.'); i >= 0 {
+		fn = fn[i+1:]
+	}
+	for {
+		i := strings.LastIndexByte(fn, '.')
+		if i < 0 {
+			return fn
+		}
+		last := fn[i+1:]
+		if !isClosureName(last) {
+			return last
+		}
+		fn = fn[:i]
+	}
+}
+
+/*isClosureName reports whether name is one the compiler gives to a closure: func1, or 2 for the nested ones.*/
+func isClosureName(name string) bool {
+	name = strings.TrimPrefix(name, "func")
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+/*callersFrom returns up to n frames from calldepth on, counted as by runtime.Caller, outermost last.*/
+func callersFrom(calldepth int, n int) []Caller {
+	pcs := make([]uintptr, n)
+	pcs = pcs[:runtime.Callers(calldepth+1, pcs)]
+	if len(pcs) == 0 {
+		return nil
+	}
+	callers := make([]Caller, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		callers = append(callers, Caller{File: frame.File, Line: frame.Line})
+		if !more {
+			return callers
+		}
+	}
+}
+
+/*
+outputLocked is output once the time and the caller are known, l.mu must be
+held. e gets the prefix of the logger unless it has one, and the logger's
+fields ahead of its own; the bytes of p follow its message. In async mode a
+closed cancel abandons an entry still waiting for room in the queue, and
+ErrTimeout is returned.
+*/
+func (l *Logger) outputLocked(e Entry, p []byte, cancel <-chan struct{}) error {
+	if l.skipEmpty && len(l.fields) == 0 && len(e.Fields) == 0 && strings.TrimSpace(e.Message) == "" && len(bytes.TrimSpace(p)) == 0 {
+		return nil
+	}
+	if e.Prefix == "" && l.prefixFunc != nil {
+		e.Prefix = l.prefixFunc()
+	}
+	if e.Prefix == "" {
+		e.Prefix = l.prefix
+	}
+	if len(l.fields) > 0 {
+		e.Fields = append(l.fields[:len(l.fields):len(l.fields)], e.Fields...)
+	}
+	if l.renderNil {
+		e.Fields = replaceNil(e.Fields, l.nilRender)
+	}
+	if l.maxFields > 0 && len(e.Fields) > l.maxFields {
+		dropped := len(e.Fields) - l.maxFields
+		e.Fields = append(e.Fields[:l.maxFields:l.maxFields], Field{Key: "_fields_truncated", Value: dropped})
+	}
+	if l.replaceUTF8 {
+		if !utf8.ValidString(e.Message) {
+			e.Message = strings.ToValidUTF8(e.Message, "\uFFFD")
+		}
+		if !utf8.Valid(p) {
+			p = bytes.ToValidUTF8(p, []byte("\uFFFD"))
+		}
+	}
+	if size := len(e.Message) + len(p); l.maxMessage > 0 && size > l.maxMessage && !l.summarizing {
+		whole := e.Message + string(p)
+		e.Message, p = truncateString(whole, l.maxMessage), nil
+		if l.truncSummary {
+			defer l.summarize(e, size, whole)
+		}
+	}
+	if l.filter != nil {
+		whole := e
+		if len(p) > 0 {
+			whole.Message += string(p)
+		}
+		if !l.filter(whole) {
+			return nil
+		}
+	}
+	if l.burst != nil {
+		msg := e.Message
+		if len(p) > 0 {
+			msg += string(p)
+		}
+		if !l.burst.keep(msg) {
+			return nil
+		}
+	}
+	if l.flag&Lseq != 0 {
+		owner := l
+		if l.seqOwner != nil {
+			owner = l.seqOwner
+		}
+		e.Seq = atomic.AddUint64(&owner.seq, 1)
+	}
+	if l.flag&Lentryid != 0 {
+		e.ID = l.entryID()
+	}
+	if l.rate != nil && !l.rate.allow(e.Time) {
+		return l.writeOverflow(e, p)
+	}
+	if global := loadGlobalHooks(); len(l.hooks) > 0 || len(global) > 0 {
+		whole := e
+		whole.Message += string(p)
+		l.runHooks(whole, global)
+	}
+	if l.handler == nil && l.formatter == nil && l.async == nil {
+		/*Kept apart from the other paths so that the entry does not escape.*/
+		l.buf = l.appendText(l.buf[:0], &e, p)
+		if l.lineSuffix != nil {
+			whole := e
+			whole.Message += string(p)
+			l.buf = l.appendSuffix(l.buf, whole)
+		}
+		_, err := l.emit(l.buf)
+		return err
+	}
+	whole := new(Entry)
+	*whole = e
+	whole.Message += string(p)
+	if q := l.async; q != nil {
+		/*The queue may block, release the lock the writer goroutine needs.*/
+		whole.Fields = append([]Field(nil), e.Fields...)
+		l.mu.Unlock()
+		var err error
+		if q.push(whole, cancel) && cancel != nil {
+			err = ErrTimeout
+		}
+		l.mu.Lock()
+		return err
+	}
+	return l.writeEntry(whole)
+}
+
+/*
+LogEntry writes an entry captured elsewhere, e.g. parsed with ParseJSONEntry,
+in the format of the logger, so that logs can be converted or merged. The
+entry keeps its time, caller and prefix, the logger's prefix standing in for
+an empty one, and passes through the level filter and the logger's fields.
+*/
+func (l *Logger) LogEntry(e Entry) error {
+	if !l.enabled(e.Level) {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.Fields = append([]Field(nil), e.Fields...)
+	return l.outputLocked(e, nil, nil)
+}
+func LogEntry(e Entry) error {
+	return gStd.LogEntry(e)
+}
+
+/*
+OutputBatch writes the entries in order, each as LogEntry writes it, under a
+single acquisition of the lock, e.g. to import events in bulk without taking
+the lock per entry. The rotation is checked once, after the last entry, so
+that the batch is not split across files and the file may grow past the
+split size by the batch. In async mode the entries are queued as usual, the
+lock being released to queue each of them, and the writer goroutine rotates
+between them as it does for the others. The first error is returned, the
+entries after it are still written.
+*/
+func (l *Logger) OutputBatch(entries []Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.async == nil {
+		/*Only in sync mode, the writer goroutine takes the lock between the entries of an async batch.*/
+		l.batching = true
+		defer func() {
+			l.batching = false
+			l.rotateIfDue()
+		}()
+	}
+	var first error
+	for _, e := range entries {
+		if !l.enabled(e.Level) {
+			continue
+		}
+		e.Fields = append([]Field(nil), e.Fields...)
+		if err := l.outputLocked(e, nil, nil); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func OutputBatch(entries []Entry) error {
+	return gStd.OutputBatch(entries)
+}
+
+/*
+OutputTimeout writes msg at level like Output, but gives up waiting after d
+so that a slow output cannot hold up a latency critical path, returning
+ErrTimeout. An entry which could not get hold of the logger or, in async
+mode, of room in the queue within d is dropped; a write already started
+cannot be interrupted and completes in the background once the output
+catches up. While a call given up on still waits for the logger, the next
+ones return ErrTimeout at once rather than pile up behind it.
+*/
+func (l *Logger) OutputTimeout(d time.Duration, level int, msg string) error {
+	return l.outputTimeout(2, d, level, msg) // +1 for this frame.
+}
+
+func OutputTimeout(d time.Duration, level int, msg string) error {
+	return gStd.outputTimeout(2, d, level, msg) // +1 for this frame.
+}
+
+/*outputTimeout is OutputTimeout for the caller at calldepth, counted as by runtime.Caller.*/
+func (l *Logger) outputTimeout(calldepth int, d time.Duration, level int, msg string) error {
+	if level != NOLEVEL && !l.enabled(level) {
+		return nil
+	}
+	if atomic.LoadInt32(&l.abandoned) > 0 {
+		return ErrTimeout
+	}
+	t := now()
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	locked := make(chan struct{})
+	go func() {
+		l.mu.Lock()
+		select {
+		case locked <- struct{}{}: // the caller takes the lock over
+		case <-ctx.Done():
+			atomic.AddInt32(&l.abandoned, -1)
+			l.mu.Unlock()
+		}
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		atomic.AddInt32(&l.abandoned, 1)
+		return ErrTimeout
+	}
+	e := Entry{Level: level, Message: msg}
+	/*Keep the lock, releasing it would mean waiting for it again.*/
+	setCaller(&e, calldepth+1, l.flag, l.headerFrames) // +1 for this frame.
+	if l.orderedTime {
+		t = now()
+	}
+	e.Time = t
+	done := make(chan error, 1)
+	go func() {
+		defer l.mu.Unlock()
+		done <- l.outputLocked(e, nil, ctx.Done())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ErrTimeout
+	}
+}
+
+/*writeEntry renders e and writes it, l.mu must be held.*/
+func (l *Logger) writeEntry(e *Entry) error {
+	if l.handler != nil {
+		return l.handle(e)
+	}
+	l.buf = l.buf[:0]
+	if l.formatter == nil {
+		l.buf = l.appendText(l.buf, e, nil)
+	} else {
+		l.buf = l.format(l.buf, e)
+	}
+	if l.lineSuffix != nil {
+		l.buf = l.appendSuffix(l.buf, *e)
+	}
+	_, err := l.emit(l.buf)
+	return err
+}
+
+/*
+format renders e with the formatter of the logger. Should the formatter
+panic, the panic is reported to the internal error writer and e is rendered
+as its header and message in the text format, leaving out the fields which
+are the likely culprits, so that a broken formatter cannot crash the caller
+while the logger is locked.
+*/
+func (l *Logger) format(buf []byte, e *Entry) (line []byte) {
+	start := len(buf)
+	defer func() {
+		if r := recover(); r != nil {
+			l.internalError("formatter panicked: %v", r)
+			text := TextFormatter{Flags: l.flag, Separator: l.headerSep, Location: l.location, Resolution: l.resolution,
+				CompactLevel: l.compactLevel, Bare: l.bare}
+			line = text.appendHeader(buf[:start], e)
+			line = append(line, e.Message...)
+			line = text.appendEnd(line, len(e.Message), nil)
+		}
+	}()
+	return l.formatter.Format(buf, e)
+}
+
+/*handle hands e to the handler of the logger, turning a panic of the handler into an error.*/
+func (l *Logger) handle(e *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			l.internalError("handler panicked: %v", r)
+			err = fmt.Errorf("glog: handler panicked: %v", r)
+		}
+	}()
+	return l.handler.Handle(e)
 }
 
-/*SetOutput sets the output destination for the logger.*/
-func (l *Logger) setOutput(w io.Writer) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.out = w
+/*appendSuffix inserts the line suffix of e before the newline ending buf.*/
+func (l *Logger) appendSuffix(buf []byte, e Entry) []byte {
+	suffix := l.lineSuffix(e)
+	if suffix == "" {
+		return buf
+	}
+	if n := len(buf); n > 0 && buf[n-1] == '\n' {
+		buf = buf[:n-1]
+	}
+	buf = append(buf, suffix...)
+	return append(buf, '\n')
 }
 
-func SetOutput(w io.Writer) {
-	gStd.mu.Lock()
-	defer gStd.mu.Unlock()
-	gStd.out = w
+/*
+colored reports whether the level tokens get ANSI escapes, l.mu must be held.
+A child of With writing through its parent follows the decision of its root,
+which owns the output, so that it stops coloring once the root is redirected.
+*/
+func (l *Logger) colored() bool {
+	if _, ok := l.out.(loggerWriter); !ok || l.seqOwner == nil {
+		return l.color
+	}
+	root := l.seqOwner
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return l.autoColor && root.color
 }
 
-/*Cheap integer to fixed-width decimal ASCII. Give a negative width to avoid zero-padding.*/
-func itoa(buf *[]byte, i int, wid int) {
-	/*Assemble decimal in reverse order.*/
-	var b [20]byte
-	bp := len(b) - 1
-	for i >= 10 || wid > 1 {
-		wid--
-		q := i / 10
-		b[bp] = byte('0' + i - q*10)
-		bp--
-		i = q
+/*appendText renders e in the default text format of the logger, the bytes of p following its message.*/
+func (l *Logger) appendText(buf []byte, e *Entry, p []byte) []byte {
+	text := TextFormatter{Flags: l.flag, Separator: l.headerSep, Color: l.colored(), LevelPad: l.levelPad, Location: l.location,
+		Resolution: l.resolution, CompactLevel: l.compactLevel, Bare: l.bare}
+	start := len(buf)
+	buf = text.appendHeader(buf, e)
+	end := len(buf)
+	buf = append(buf, e.Message...)
+	buf = append(buf, p...)
+	if l.headerPerLine {
+		buf = repeatHeader(buf, start, end)
 	}
-	// i < 10
-	b[bp] = byte('0' + i)
-	*buf = append(*buf, b[bp:]...)
+	return text.appendEnd(buf, len(buf)-end, e.Fields)
 }
 
-/*
-formatHeader writes log header to buf in following order:
-  * l.prefix (if it's not blank),
-  * date and/or time (if corresponding flags are provided),
-  * file and line number (if corresponding flags are provided).
-*/
-func (l *Logger) formatHeader(buf *[]byte, t time.Time, file string, line int) {
-	*buf = append(*buf, l.prefix...)
-	if l.flag&(Ldate|Ltime|Lmicroseconds) != 0 {
-		if l.flag&LUTC != 0 {
-			t = t.UTC()
-		}
-		if l.flag&Ldate != 0 {
-			year, month, day := t.Date()
-			itoa(buf, year, 4)
-			*buf = append(*buf, '/')
-			itoa(buf, int(month), 2)
-			*buf = append(*buf, '/')
-			itoa(buf, day, 2)
-			*buf = append(*buf, ' ')
-		}
-		if l.flag&(Ltime|Lmicroseconds) != 0 {
-			hour, min, sec := t.Clock()
-			itoa(buf, hour, 2)
-			*buf = append(*buf, ':')
-			itoa(buf, min, 2)
-			*buf = append(*buf, ':')
-			itoa(buf, sec, 2)
-			if l.flag&Lmicroseconds != 0 {
-				*buf = append(*buf, '.')
-				itoa(buf, t.Nanosecond()/1e3, 6)
-			}
-			*buf = append(*buf, ' ')
+/*emit writes a formatted line and hands it to the line callbacks, l.mu must be held.*/
+func (l *Logger) emit(line []byte) (int, error) {
+	n, err := l.write(line)
+	if err == nil {
+		for _, c := range l.lineCallbacks {
+			l.callLine(c.fn, append([]byte(nil), line...))
 		}
 	}
-	if l.flag&(Lshortfile|Llongfile) != 0 {
-		if l.flag&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
+	return n, err
+}
+
+/*callLine hands line to the line callback fn, reporting rather than propagating its panics.*/
+func (l *Logger) callLine(fn func(line []byte), line []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			l.internalError("line callback panicked: %v", r)
 		}
-		*buf = append(*buf, file...)
-		*buf = append(*buf, ':')
-		itoa(buf, line, -1)
-		*buf = append(*buf, ": "...)
-	}
+	}()
+	fn(line)
 }
 
 /*
-Output writes the output for a logging event. The string s contains
-the text to print after the prefix specified by the flags of the
-Logger. A newline is appended if the last character of s is not
-already a newline. Calldepth is used to recover the PC and is
-provided for generality, although at the moment on all pre-defined
-paths it will be 2.
+write hands a formatted line to the output, accounts its size and rotates when due, l.mu must be held.
+A line reaching the split size on its own is given a file of its own: the active file is rotated
+first unless empty, so that the oversized line does not drag the lines before it along.
 */
-func (l *Logger) Output(calldepth int, s string) error {
-	now := time.Now() // get this early.
-	var file string
-	var line int
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if l.flag&(Lshortfile|Llongfile) != 0 {
-		/*Release lock while getting caller info - it's expensive.*/
-		l.mu.Unlock()
-		var ok bool
-		_, file, line, ok = runtime.Caller(calldepth)
-		if !ok {
-			file = "???"
-			line = 0
+func (l *Logger) write(line []byte) (int, error) {
+	if !l.batching && l.splitFileSize > 0 && uint64(len(line)) >= l.splitFileSize && l.writtenSize > 0 && l.filename != "" {
+		if err := l.rotate(); err != nil {
+			l.internalError("cannot rotate %s: %v", l.filename, err)
 		}
-		l.mu.Lock()
 	}
-	l.buf = l.buf[:0]
-	l.formatHeader(&l.buf, now, file, line)
-	l.buf = append(l.buf, s...)
-	if len(s) == 0 || s[len(s)-1] != '\n' {
-		l.buf = append(l.buf, '\n')
+	n, err := l.out.Write(line)
+	if err != nil {
+		l.lastErrTime, l.lastErr = now(), err
+	} else {
+		l.lastErrTime, l.lastErr = time.Time{}, nil
 	}
-	n, err := l.out.Write(l.buf)
 	l.writtenSize += uint64(n)
-	if l.writtenSize >= l.splitFileSize {
+	l.writtenLines++
+	if !l.batching {
+		l.rotateIfDue()
+	}
+	return n, err
+}
+
+/*rotateIfDue rotates once the split size or the line count is reached, l.mu must be held.*/
+func (l *Logger) rotateIfDue() {
+	if l.splitFileSize > 0 && l.writtenSize >= l.splitFileSize || l.lineRotate > 0 && l.writtenLines >= l.lineRotate {
 		if l.filename != "" {
-			l.rotate()
+			if err := l.rotate(); err != nil {
+				l.internalError("cannot rotate %s: %v", l.filename, err)
+			}
+		} else {
+			l.writtenSize = 0
+			l.writtenLines = 0
 		}
-		l.writtenSize = 0
 	}
-	return err
-}
-func Output(calldepth int, s string) error {
-	return gStd.Output(calldepth+1, s) // +1 for this frame.
 }
 
 /*#################### S u g a r #####################*/
 func (l *Logger) Debug(format string, v ...interface{}) {
-	l.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[DEBUG], format), v...))
+	if l.enabled(DEBUG) {
+		l.output(2, DEBUG, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 func Debug(format string, v ...interface{}) {
-	gStd.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[DEBUG], format), v...))
+	if gStd.enabled(DEBUG) {
+		gStd.output(2, DEBUG, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[INFO], format), v...))
+	if l.enabled(INFO) {
+		l.output(2, INFO, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 func Info(format string, v ...interface{}) {
-	gStd.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[INFO], format), v...))
+	if gStd.enabled(INFO) {
+		gStd.output(2, INFO, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 func (l *Logger) Warn(format string, v ...interface{}) {
-	l.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[WARNING], format), v...))
+	if l.enabled(WARNING) {
+		l.output(2, WARNING, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 func Warn(format string, v ...interface{}) {
-	gStd.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[WARNING], format), v...))
+	if gStd.enabled(WARNING) {
+		gStd.output(2, WARNING, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 func (l *Logger) Err(format string, v ...interface{}) {
-	l.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[ERROR], format), v...))
+	if l.enabled(ERROR) {
+		if fields, ok := l.coolDown(format, nil); ok {
+			l.output(2, ERROR, fields, fmt.Sprintf(format, v...), nil)
+		}
+	}
 }
 func Err(format string, v ...interface{}) {
-	gStd.Output(2, fmt.Sprintf(fmt.Sprintf("[%s]:%s", levelStr[ERROR], format), v...))
+	if gStd.enabled(ERROR) {
+		if fields, ok := gStd.coolDown(format, nil); ok {
+			gStd.output(2, ERROR, fields, fmt.Sprintf(format, v...), nil)
+		}
+	}
+}
+
+/*
+InfoNoCaller logs at INFO as Info does, but without looking up the caller
+whatever the flags, so the line has neither file:line nor function name and
+the call saves the cost of runtime.Caller, for the hot paths logging often.
+DebugNoCaller, WarnNoCaller and ErrNoCaller are alike for their levels.
+*/
+func (l *Logger) InfoNoCaller(format string, v ...interface{}) {
+	if l.enabled(INFO) {
+		l.output(noCaller, INFO, nil, fmt.Sprintf(format, v...), nil)
+	}
+}
+func InfoNoCaller(format string, v ...interface{}) {
+	if gStd.enabled(INFO) {
+		gStd.output(noCaller, INFO, nil, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (l *Logger) DebugNoCaller(format string, v ...interface{}) {
+	if l.enabled(DEBUG) {
+		l.output(noCaller, DEBUG, nil, fmt.Sprintf(format, v...), nil)
+	}
+}
+func DebugNoCaller(format string, v ...interface{}) {
+	if gStd.enabled(DEBUG) {
+		gStd.output(noCaller, DEBUG, nil, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (l *Logger) WarnNoCaller(format string, v ...interface{}) {
+	if l.enabled(WARNING) {
+		l.output(noCaller, WARNING, nil, fmt.Sprintf(format, v...), nil)
+	}
+}
+func WarnNoCaller(format string, v ...interface{}) {
+	if gStd.enabled(WARNING) {
+		gStd.output(noCaller, WARNING, nil, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (l *Logger) ErrNoCaller(format string, v ...interface{}) {
+	if l.enabled(ERROR) {
+		if fields, ok := l.coolDown(format, nil); ok {
+			l.output(noCaller, ERROR, fields, fmt.Sprintf(format, v...), nil)
+		}
+	}
+}
+func ErrNoCaller(format string, v ...interface{}) {
+	if gStd.enabled(ERROR) {
+		if fields, ok := gStd.coolDown(format, nil); ok {
+			gStd.output(noCaller, ERROR, fields, fmt.Sprintf(format, v...), nil)
+		}
+	}
+}
+
+/*
+WrapErr logs err at ERROR with the context given by format and v, and returns
+err wrapped with the same context, so that errors.Is and errors.As still see it:
+
+	return logger.WrapErr(err, "loading %s", path)
+
+A nil err logs nothing and returns nil.
+*/
+func (l *Logger) WrapErr(err error, format string, v ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	msg := fmt.Sprintf(format, v...)
+	if l.enabled(ERROR) {
+		if fields, ok := l.coolDown(format, nil); ok {
+			l.output(2, ERROR, fields, msg+": "+err.Error(), nil)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+func WrapErr(err error, format string, v ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	msg := fmt.Sprintf(format, v...)
+	if gStd.enabled(ERROR) {
+		if fields, ok := gStd.coolDown(format, nil); ok {
+			gStd.output(2, ERROR, fields, msg+": "+err.Error(), nil)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+/*Mark returns the current time of the logger's clock, to be passed to InfoSince.*/
+func (l *Logger) Mark() time.Time {
+	return now()
+}
+
+/*InfoSince logs at INFO level like l.Info with the time elapsed since start as the "elapsed" field.*/
+func (l *Logger) InfoSince(start time.Time, format string, v ...interface{}) {
+	if l.enabled(INFO) {
+		l.output(2, INFO, []Field{{Key: "elapsed", Value: now().Sub(start)}}, fmt.Sprintf(format, v...), nil)
+	}
+}
+func InfoSince(start time.Time, format string, v ...interface{}) {
+	if gStd.enabled(INFO) {
+		gStd.output(2, INFO, []Field{{Key: "elapsed", Value: now().Sub(start)}}, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 /*
-Printf calls l.Output to print to the logger.
+Printf prints to the logger, at the level set with SetPrintLevel.
 Arguments are handled in the manner of fmt.Printf.
 */
 func (l *Logger) Printf(format string, v ...interface{}) {
-	l.Output(2, fmt.Sprintf(format, v...))
+	if level := l.printLevelOf(); l.enabled(level) {
+		l.output(2, level, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 func Printf(format string, v ...interface{}) {
-	gStd.Output(2, fmt.Sprintf(format, v...))
+	if level := gStd.printLevelOf(); gStd.enabled(level) {
+		gStd.output(2, level, nil, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 /*
-Print calls l.Output to print to the logger.
+Print prints to the logger, at the level set with SetPrintLevel.
 Arguments are handled in the manner of fmt.Print.
 */
-func (l *Logger) Print(v ...interface{}) { l.Output(2, fmt.Sprint(v...)) }
+func (l *Logger) Print(v ...interface{}) {
+	if level := l.printLevelOf(); l.enabled(level) {
+		l.output(2, level, nil, fmt.Sprint(v...), nil)
+	}
+}
 func Print(v ...interface{}) {
-	gStd.Output(2, fmt.Sprint(v...))
+	if level := gStd.printLevelOf(); gStd.enabled(level) {
+		gStd.output(2, level, nil, fmt.Sprint(v...), nil)
+	}
 }
 
 /*
-Println calls l.Output to print to the logger.
+Println prints to the logger, at the level set with SetPrintLevel.
 Arguments are handled in the manner of fmt.Println.
 */
-func (l *Logger) Println(v ...interface{}) { l.Output(2, fmt.Sprintln(v...)) }
+func (l *Logger) Println(v ...interface{}) {
+	if level := l.printLevelOf(); l.enabled(level) {
+		l.output(2, level, nil, fmt.Sprintln(v...), nil)
+	}
+}
 func Println(v ...interface{}) {
-	gStd.Output(2, fmt.Sprintln(v...))
+	if level := gStd.printLevelOf(); gStd.enabled(level) {
+		gStd.output(2, level, nil, fmt.Sprintln(v...), nil)
+	}
+}
+
+/*fatalMu serializes the fatal paths, the first caller writes its line and exits while the others wait.*/
+var fatalMu sync.Mutex
+
+/*fatal writes s at level, then exits the process with code.*/
+func (l *Logger) fatal(code int, level int, s string) {
+	fatalMu.Lock()
+	defer fatalMu.Unlock() // only reached when exit is stubbed
+	l.output(3, level, nil, s, nil)
+	l.exit(code)
 }
 
 /*Fatal is equivalent to l.Print() followed by a call to os.Exit(1).*/
 func (l *Logger) Fatal(v ...interface{}) {
-	l.Output(2, fmt.Sprint(v...))
-	os.Exit(1)
+	l.fatal(1, NOLEVEL, fmt.Sprint(v...))
 }
 func Fatal(v ...interface{}) {
-	gStd.Output(2, fmt.Sprint(v...))
-	os.Exit(1)
+	gStd.fatal(1, NOLEVEL, fmt.Sprint(v...))
 }
 
 /*Fatalf is equivalent to l.Printf() followed by a call to os.Exit(1).*/
 func (l *Logger) Fatalf(format string, v ...interface{}) {
-	l.Output(2, fmt.Sprintf(format, v...))
-	os.Exit(1)
+	l.fatal(1, NOLEVEL, fmt.Sprintf(format, v...))
 }
 func Fatalf(format string, v ...interface{}) {
-	gStd.Output(2, fmt.Sprintf(format, v...))
-	os.Exit(1)
+	gStd.fatal(1, NOLEVEL, fmt.Sprintf(format, v...))
 }
 
 /*Fatalln is equivalent to l.Println() followed by a call to os.Exit(1).*/
 func (l *Logger) Fatalln(v ...interface{}) {
-	l.Output(2, fmt.Sprintln(v...))
-	os.Exit(1)
+	l.fatal(1, NOLEVEL, fmt.Sprintln(v...))
 }
 func Fatalln(v ...interface{}) {
-	gStd.Output(2, fmt.Sprintln(v...))
-	os.Exit(1)
+	gStd.fatal(1, NOLEVEL, fmt.Sprintln(v...))
+}
+
+/*FatalCode logs at FATAL level like l.Print() then exits the process with the given code.*/
+func (l *Logger) FatalCode(code int, v ...interface{}) {
+	l.fatal(code, FATAL, fmt.Sprint(v...))
+}
+func FatalCode(code int, v ...interface{}) {
+	gStd.fatal(code, FATAL, fmt.Sprint(v...))
 }
 
 /*Panic is equivalent to l.Print() followed by a call to panic().*/
 func (l *Logger) Panic(v ...interface{}) {
 	s := fmt.Sprint(v...)
 	l.Output(2, s)
-	panic(s)
+	panic(l.makePanicValue(s))
 }
 func Panic(v ...interface{}) {
 	s := fmt.Sprint(v...)
 	gStd.Output(2, s)
-	panic(s)
+	panic(gStd.makePanicValue(s))
 }
 
 /*Panicf is equivalent to l.Printf() followed by a call to panic().*/
 func (l *Logger) Panicf(format string, v ...interface{}) {
 	s := fmt.Sprintf(format, v...)
 	l.Output(2, s)
-	panic(s)
+	panic(l.makePanicValue(s))
 }
 func Panicf(format string, v ...interface{}) {
 	s := fmt.Sprintf(format, v...)
 	gStd.Output(2, s)
-	panic(s)
+	panic(gStd.makePanicValue(s))
 }
 
 /*Panicln is equivalent to l.Println() followed by a call to panic().*/
 func (l *Logger) Panicln(v ...interface{}) {
 	s := fmt.Sprintln(v...)
 	l.Output(2, s)
-	panic(s)
+	panic(l.makePanicValue(s))
 }
 func Panicln(v ...interface{}) {
 	s := fmt.Sprintln(v...)
 	gStd.Output(2, s)
-	panic(s)
+	panic(gStd.makePanicValue(s))
+}
+
+/*
+SetPanicValue sets the function building the value that Panic, Panicf and Panicln
+hand to panic() from the logged message, e.g. a custom error type.
+A nil fn restores the default, which panics with the message string itself.
+*/
+func (l *Logger) SetPanicValue(fn func(msg string) interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.panicValue = fn
+}
+
+func SetPanicValue(fn func(msg string) interface{}) {
+	gStd.SetPanicValue(fn)
+}
+
+/*makePanicValue returns the value to panic with for the message s.*/
+func (l *Logger) makePanicValue(s string) interface{} {
+	l.mu.Lock()
+	fn := l.panicValue
+	l.mu.Unlock()
+	if fn == nil {
+		return s
+	}
+	return fn(s)
 }
 
 /*Flags returns the output flags for the logger.*/
@@ -396,6 +1971,200 @@ func SetFlags(flag int) {
 	gStd.SetFlags(flag)
 }
 
+/*
+SetHeaderSeparator sets the separator written after the date and after the time
+of the header, e.g. "|" for column parsing. The default is a single space.
+*/
+func (l *Logger) SetHeaderSeparator(sep string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.headerSep = sep
+}
+
+func SetHeaderSeparator(sep string) {
+	gStd.SetHeaderSeparator(sep)
+}
+
+/*
+SetLocation sets the time zone of the date and time in the header, e.g. the
+result of time.LoadLocation("Asia/Shanghai"), whatever the local zone of the
+host. LUTC takes precedence over it and a nil loc restores the local zone.
+It applies to the default text format, a Formatter renders the time its own way.
+*/
+func (l *Logger) SetLocation(loc *time.Location) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.location = loc
+}
+
+func SetLocation(loc *time.Location) {
+	gStd.SetLocation(loc)
+}
+
+/*
+SetTimeResolution truncates the time of the header to a multiple of d, e.g.
+100*time.Millisecond, so that the lines of a bucket share their timestamp for
+the aggregators grouping by it. With Lmicroseconds the fraction of the second
+only shows the digits d carries, ".1" for 100ms, none from a second on. Zero
+restores the full precision. It applies to the default text format.
+*/
+func (l *Logger) SetTimeResolution(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resolution = d
+}
+
+func SetTimeResolution(d time.Duration) {
+	gStd.SetTimeResolution(d)
+}
+
+/*
+SetHeaderPerLine makes the text format repeat the header, prefix and level
+token included, on every physical line of a multi-line message such as a
+stack trace, for the parsers which need each line to carry one. By default
+the lines following the first one are written as they are.
+*/
+func (l *Logger) SetHeaderPerLine(perLine bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.headerPerLine = perLine
+}
+
+func SetHeaderPerLine(perLine bool) {
+	gStd.SetHeaderPerLine(perLine)
+}
+
+/*
+SetFormatter sets the Formatter rendering the entries of the logger,
+e.g. a *JSONFormatter. A nil f restores the default text format, which
+follows the flags and the header separator of the logger.
+*/
+func (l *Logger) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.formatter = f
+}
+
+func SetFormatter(f Formatter) {
+	gStd.SetFormatter(f)
+}
+
+/*Level returns the minimum level written by the logger.*/
+func (l *Logger) Level() int {
+	return int(atomic.LoadInt32(&l.level))
+}
+func Level() int {
+	return gStd.Level()
+}
+
+/*
+SetLevel sets the minimum level written by the logger, entries below it are dropped
+before their message is formatted. The default DEBUG writes everything; the entries
+without a level, from Output and the Print, Fatal and Panic families, are always written.
+*/
+func (l *Logger) SetLevel(level int) {
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+func SetLevel(level int) {
+	gStd.SetLevel(level)
+}
+
+/*
+SetPrintLevel gives the lines of Print, Printf and Println a level, so that
+they get its token and are filtered like the other entries of that level.
+The default NOLEVEL writes them without a token, whatever the level filter.
+*/
+func (l *Logger) SetPrintLevel(level int) {
+	atomic.StoreInt32(&l.printLevel, int32(level-NOLEVEL))
+}
+
+func SetPrintLevel(level int) {
+	gStd.SetPrintLevel(level)
+}
+
+/*printLevelOf returns the level of the Print family.*/
+func (l *Logger) printLevelOf() int {
+	return int(atomic.LoadInt32(&l.printLevel)) + NOLEVEL
+}
+
+/*
+enabled reports whether an entry of the given level passes the level filter
+and the sampling, once per entry as the sampling counts the calls.
+*/
+func (l *Logger) enabled(level int) bool {
+	if level == NOLEVEL {
+		return true
+	}
+	if level < int(atomic.LoadInt32(&l.level)) {
+		return false
+	}
+	if s, _ := l.sampling.Load().(*levelSampling); s != nil {
+		return s.keep(level)
+	}
+	return true
+}
+
+/*
+SetLevelPadding pads the level name of the text format to width so that the columns
+following it line up, e.g. "[INFO ]:" and "[DEBUG]:" with -5. A positive width pads
+on the left, a negative one on the right and 0, the default, does not pad.
+*/
+func (l *Logger) SetLevelPadding(width int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.levelPad = width
+}
+
+func SetLevelPadding(width int) {
+	gStd.SetLevelPadding(width)
+}
+
+/*
+SetCompactLevel makes the text format write the level token as its first
+letter and a space, "I message" rather than "[INFO]: message", for the high
+volume logs where every byte counts. The level padding does not apply to it.
+*/
+func (l *Logger) SetCompactLevel(compact bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.compactLevel = compact
+}
+
+func SetCompactLevel(compact bool) {
+	gStd.SetCompactLevel(compact)
+}
+
+/*
+SetBare makes the text format write the message alone, without the prefix,
+the header whatever the flags and the level token, so that a line is exactly
+the message passed followed by the fields of the entry, if any, and a newline.
+*/
+func (l *Logger) SetBare(bare bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bare = bare
+}
+
+func SetBare(bare bool) {
+	gStd.SetBare(bare)
+}
+
+/*
+SetSkipEmpty sets whether entries whose message is empty or only white space
+are dropped instead of being written as a line without text. The entries
+carrying fields, their own or those of the logger, are still written.
+*/
+func (l *Logger) SetSkipEmpty(skip bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.skipEmpty = skip
+}
+
+func SetSkipEmpty(skip bool) {
+	gStd.SetSkipEmpty(skip)
+}
+
 /*Prefix returns the output prefix for the logger.*/
 func (l *Logger) Prefix() string {
 	l.mu.Lock()
@@ -417,6 +2186,23 @@ func SetPrefix(prefix string) {
 	gStd.SetPrefix(prefix)
 }
 
+/*
+SetPrefixFunc sets a function computing the prefix of each line, e.g. from
+the request being served, run before the line is queued or written. It takes
+precedence over the prefix of SetPrefix, which is written when f returns "".
+It is called with the logger locked, so it must be cheap and must not log.
+A nil f leaves the static prefix alone again.
+*/
+func (l *Logger) SetPrefixFunc(f func() string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prefixFunc = f
+}
+
+func SetPrefixFunc(f func() string) {
+	gStd.SetPrefixFunc(f)
+}
+
 // Writer returns the output destination for the logger.
 func (l *Logger) Writer() io.Writer {
 	l.mu.Lock()
@@ -426,3 +2212,29 @@ func (l *Logger) Writer() io.Writer {
 func Writer() io.Writer {
 	return gStd.Writer()
 }
+
+/*
+FlushString returns what the logger wrote to its bytes.Buffer output so far
+and empties the buffer, waiting for the queue in async mode. It is meant for
+the tests asserting what was logged, and returns ErrNotBuffer for the other outputs.
+*/
+func (l *Logger) FlushString() (string, error) {
+	l.mu.Lock()
+	q := l.async
+	l.mu.Unlock()
+	if q != nil {
+		q.flush()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	buf, ok := l.out.(*bytes.Buffer)
+	if !ok {
+		return "", ErrNotBuffer
+	}
+	s := buf.String()
+	buf.Reset()
+	return s, nil
+}
+func FlushString() (string, error) {
+	return gStd.FlushString()
+}
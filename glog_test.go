@@ -1,8 +1,20 @@
 package glog
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -44,4 +56,1324 @@ func TestNewEx(t *testing.T) {
 		}(10000)
 	}
 	Wg.Wait()
-}
\ No newline at end of file
+}
+
+/*TestOutputRace stresses concurrent writes across rotations, run it with "go test -race".*/
+func TestOutputRace(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "testC.log")
+	var logger = NewEx(filename, "[Info] ", Ldate|Ltime|Lshortfile, 1, 1000)
+	logger.splitFileSize = 64 * 1024
+
+	const goroutines, count = 10, 2000
+	var Wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		Wg.Add(1)
+		go func(count int) {
+			for i := 0; i < count; i++ {
+				logger.Printf("%s-%d", "abcdefghijklmnopqrstuvwxyz", 123456789)
+			}
+			Wg.Done()
+		}(count)
+	}
+	Wg.Wait()
+
+	matches, _ := filepath.Glob(filename + "*")
+	if len(matches) < 2 {
+		t.Fatalf("expected rotated files, got %v", matches)
+	}
+	lines := 0
+	for _, name := range matches {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			if !strings.HasSuffix(line, ": abcdefghijklmnopqrstuvwxyz-123456789") {
+				t.Fatalf("corrupted line %q in %s", line, name)
+			}
+			lines++
+		}
+	}
+	if lines != goroutines*count {
+		t.Errorf("got %d lines, want %d", lines, goroutines*count)
+	}
+}
+
+type panicError struct {
+	msg string
+}
+
+func (e *panicError) Error() string { return e.msg }
+
+func TestSetPanicValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[Info] ", 0)
+	logger.SetPanicValue(func(msg string) interface{} { return &panicError{msg: "[FATAL]:" + msg} })
+	defer func() {
+		e, ok := recover().(*panicError)
+		if !ok {
+			t.Fatalf("recovered %T, want *panicError", e)
+		}
+		if e.msg != "[FATAL]:boom 42" {
+			t.Errorf("panic value %q", e.msg)
+		}
+		if buf.String() != "[Info] boom 42\n" {
+			t.Errorf("logged %q", buf.String())
+		}
+	}()
+	logger.Panicf("boom %d", 42)
+}
+
+func TestAddLineCallback(t *testing.T) {
+	var buf bytes.Buffer
+	var lines []string
+	logger := newEx(&buf, "[Info] ", 0)
+	logger.AddLineCallback(func(line []byte) { lines = append(lines, string(line)) })
+	logger.Println("Hello!")
+	logger.Printf("%s-%d", "abc", 123)
+	logger.Print("你好\n")
+
+	want := []string{"[Info] Hello!\n", "[Info] abc-123\n", "[Info] 你好\n"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want[i])
+		}
+	}
+	if buf.String() != strings.Join(want, "") {
+		t.Errorf("output %q", buf.String())
+	}
+}
+
+func TestNewExNoRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testD.log")
+	var logger = NewEx(filename, "[Info] ", Ldate|Ltime, 0, 5)
+	for i := 0; i < 1000; i++ {
+		logger.Println("abcdefghijklmnopqrstuvwxyz0123456789你好，我是测试日志~!@#$%^&*()_+{}|:")
+	}
+	matches, _ := filepath.Glob(filename + ".*")
+	if len(matches) != 0 {
+		t.Errorf("unexpected rotated files %v", matches)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 1000 {
+		t.Errorf("got %d lines, want 1000", n)
+	}
+}
+
+func TestOutputBytes(t *testing.T) {
+	var strBuf, byteBuf bytes.Buffer
+	strLogger := newEx(&strBuf, "[Info] ", 0)
+	byteLogger := newEx(&byteBuf, "[Info] ", 0)
+	for _, msg := range []string{"Hello!", "Glog!\n", "", "你好"} {
+		strLogger.Output(1, msg)
+		byteLogger.OutputBytes(1, []byte(msg))
+	}
+	if strBuf.String() != byteBuf.String() {
+		t.Errorf("OutputBytes wrote %q, Output wrote %q", byteBuf.String(), strBuf.String())
+	}
+}
+
+func BenchmarkOutput(b *testing.B) {
+	logger := newEx(io.Discard, "[Info] ", LstdFlags)
+	msg := []byte("abcdefghijklmnopqrstuvwxyz0123456789你好，我是测试日志~!@#$%^&*()_+{}|:")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Output(1, string(msg))
+	}
+}
+
+func BenchmarkOutputBytes(b *testing.B) {
+	logger := newEx(io.Discard, "[Info] ", LstdFlags)
+	msg := []byte("abcdefghijklmnopqrstuvwxyz0123456789你好，我是测试日志~!@#$%^&*()_+{}|:")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.OutputBytes(1, msg)
+	}
+}
+
+func TestSetHeaderSeparator(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Ldate|Ltime|Lshortfile)
+	logger.SetHeaderSeparator("|")
+	logger.Println("Hello!")
+	got := buf.String()
+	// 2009/01/23|01:23:23|glog_test.go:23: Hello!
+	if len(got) < 20 || got[10] != '|' || got[19] != '|' || !strings.HasPrefix(got[20:], "glog_test.go:") {
+		t.Errorf("got %q, want pipe separated header", got)
+	}
+	if strings.Contains(got[:20], " ") {
+		t.Errorf("got %q, still space separated", got)
+	}
+}
+
+type rotateSink struct {
+	bytes.Buffer
+	rotated []string
+}
+
+func (s *rotateSink) OnRotate(oldPath string) { s.rotated = append(s.rotated, oldPath) }
+
+func TestRotateNotifier(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testE.log")
+	var logger = NewEx(filename, "[Info] ", 0, 1, 5)
+	logger.splitFileSize = 64
+	sink := &rotateSink{}
+	logger.SetOutput(sink)
+	for i := 0; i < 8; i++ {
+		logger.Printf("%s-%d", "abcdefghijklmnopqrstuvwxyz", i)
+	}
+	// every line is 36 bytes, so each second line rotates
+	want := []string{filename + ".0", filename + ".1", filename + ".2", filename + ".3"}
+	if len(sink.rotated) != len(want) {
+		t.Fatalf("rotated %v, want %v", sink.rotated, want)
+	}
+	for i := range want {
+		if sink.rotated[i] != want[i] {
+			t.Errorf("rotation %d archived to %q, want %q", i, sink.rotated[i], want[i])
+		}
+	}
+	if logger.Writer() != io.Writer(sink) {
+		t.Error("rotation replaced the custom output")
+	}
+
+	// nothing was archived, so there is nothing to hear about
+	rename = func(src, dst string) error { return &os.LinkError{Op: "rename", Old: src, New: dst, Err: os.ErrPermission} }
+	defer func() { rename = os.Rename }()
+	logger.SetInternalErrorWriter(io.Discard)
+	logger.Rotate()
+	if len(sink.rotated) != len(want) {
+		t.Errorf("failed rotation notified %v", sink.rotated[len(want):])
+	}
+}
+
+func TestFatalCode(t *testing.T) {
+	var code int
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.FatalCode(3, "config missing")
+	if code != 3 {
+		t.Errorf("exit code %d, want 3", code)
+	}
+	if buf.String() != "[FATAL]: config missing\n" {
+		t.Errorf("logged %q", buf.String())
+	}
+	logger.Fatal("boom")
+	if code != 1 {
+		t.Errorf("Fatal exit code %d, want 1", code)
+	}
+}
+
+func TestSetFollowSymlink(t *testing.T) {
+	for _, follow := range []bool{false, true} {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "target.log")
+		link := filepath.Join(dir, "link.log")
+		if err := os.WriteFile(target, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, link); err != nil {
+			t.Skip("symlinks not supported:", err)
+		}
+		var logger = New(link, "", 0)
+		logger.SetFollowSymlink(follow)
+		logger.Println("before")
+		if err := logger.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		logger.Println("after")
+
+		fi, err := os.Lstat(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		isLink := fi.Mode()&os.ModeSymlink != 0
+		archive := link + ".0"
+		if follow {
+			archive = target + ".0"
+		}
+		if data, _ := os.ReadFile(archive); string(data) != "before\n" {
+			t.Errorf("follow=%v: archive %s holds %q", follow, archive, data)
+		}
+		if data, _ := os.ReadFile(link); string(data) != "after\n" {
+			t.Errorf("follow=%v: active file holds %q", follow, data)
+		}
+		if isLink != follow {
+			t.Errorf("follow=%v: filename is a symlink: %v", follow, isLink)
+		}
+	}
+}
+
+func TestRotateResetsWrittenSize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testH.log")
+	var logger = NewEx(filename, "", 0, 1, 5)
+	logger.Println("Hello!")
+	if logger.writtenSize != 7 {
+		t.Fatalf("written size %d, want 7", logger.writtenSize)
+	}
+	if err := logger.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if logger.writtenSize != 0 {
+		t.Errorf("written size %d after Rotate, want 0", logger.writtenSize)
+	}
+	logger.Println("Glog!")
+	if logger.writtenSize != 6 {
+		t.Errorf("written size %d, want 6", logger.writtenSize)
+	}
+	if _, err := os.Stat(filename + ".1"); err == nil {
+		t.Error("rotated again right after Rotate")
+	}
+}
+
+func TestSetSkipEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[Info] ", 0)
+	logger.Print("")
+	logger.Println()
+	if buf.String() != "[Info] \n[Info] \n" {
+		t.Errorf("default wrote %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetSkipEmpty(true)
+	logger.Print("")
+	logger.Println()
+	logger.OutputBytes(1, []byte(" \t\n"))
+	logger.Info("")
+	logger.LogEntry(Entry{Level: INFO, Message: " "})
+	logger.OutputBatch([]Entry{{Level: INFO}})
+	logger.OutputTimeout(time.Second, INFO, "")
+	logger.Println("Hello!")
+	if buf.String() != "[Info] Hello!\n" {
+		t.Errorf("with SetSkipEmpty wrote %q", buf.String())
+	}
+
+	// the fields are worth a line of their own
+	buf.Reset()
+	logger.With("user", "yax").Info("")
+	logger.LogEntry(Entry{Level: INFO, Fields: []Field{{Key: "count", Value: 5}}})
+	if buf.String() != "[Info] [INFO]:  user=yax\n[Info] [INFO]:  count=5\n" {
+		t.Errorf("with SetSkipEmpty and fields wrote %q", buf.String())
+	}
+}
+
+func TestInfoSince(t *testing.T) {
+	clock := time.Date(2009, 1, 23, 1, 23, 23, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	start := logger.Mark()
+	clock = clock.Add(1500 * time.Millisecond)
+	logger.InfoSince(start, "op %s", "done")
+	if buf.String() != "[INFO]: op done elapsed=1.5s\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestBytesUntilRotate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testI.log")
+	logger := NewEx(filename, "", 0, 1, 5)
+	logger.Println("Hello!")
+	if got := logger.BytesUntilRotate(); got != 1024*1024-7 {
+		t.Errorf("BytesUntilRotate() = %d, want %d", got, 1024*1024-7)
+	}
+	if got := NewEx(filepath.Join(t.TempDir(), "testJ.log"), "", 0, 0, 5).BytesUntilRotate(); got != math.MaxUint64 {
+		t.Errorf("BytesUntilRotate() = %d without rotation", got)
+	}
+	var buf bytes.Buffer
+	logger = newEx(&buf, "", 0)
+	logger.SetSplitBytes(16)
+	logger.Println("Hello!")
+	if got := logger.BytesUntilRotate(); got != math.MaxUint64 {
+		t.Errorf("BytesUntilRotate() = %d without a file", got)
+	}
+}
+
+func TestAutoColorSwapOutput(t *testing.T) {
+	var tty, file bytes.Buffer
+	isTerminal = func(w io.Writer) bool { return w == &tty }
+	defer func() { isTerminal = terminal }()
+
+	logger := newEx(&tty, "", 0)
+	logger.SetAutoColor(true)
+	logger.Info("colored")
+	if !strings.Contains(tty.String(), "\x1b[") {
+		t.Errorf("no escapes on the terminal: %q", tty.String())
+	}
+	if old := logger.SwapOutput(&file); old != &tty {
+		t.Errorf("SwapOutput returned %v", old)
+	}
+	logger.Info("plain")
+	if file.String() != "[INFO]: plain\n" {
+		t.Errorf("after SwapOutput got %q", file.String())
+	}
+	logger.SetOutput(&tty)
+	tty.Reset()
+	logger.Info("colored")
+	if !strings.Contains(tty.String(), "\x1b[") {
+		t.Errorf("no escapes after SetOutput back to the terminal: %q", tty.String())
+	}
+
+	// a child follows the output of its root
+	child := logger.With("k", "v")
+	logger.SwapOutput(&file)
+	file.Reset()
+	child.Info("hi")
+	if file.String() != "[INFO]: hi k=v\n" {
+		t.Errorf("child after SwapOutput got %q", file.String())
+	}
+	logger.SwapOutput(&tty)
+	tty.Reset()
+	child.With("n", 1).Info("hi")
+	if !strings.Contains(tty.String(), "\x1b[") {
+		t.Errorf("no escapes from the children back on the terminal: %q", tty.String())
+	}
+}
+
+func TestOutputTimeout(t *testing.T) {
+	w := &gateWriter{gate: make(chan struct{}), entered: make(chan struct{}, 1)}
+	logger := newEx(w, "", Lshortfile)
+	if err := logger.OutputTimeout(10*time.Millisecond, INFO, "slow"); err != ErrTimeout {
+		t.Errorf("blocked write returned %v, want ErrTimeout", err)
+	}
+	<-w.entered
+	// the write of "slow" holds the logger, so this one is dropped
+	if err := logger.OutputTimeout(10*time.Millisecond, INFO, "late"); err != ErrTimeout {
+		t.Errorf("write waiting for the logger returned %v, want ErrTimeout", err)
+	}
+	start := time.Now()
+	if err := logger.OutputTimeout(time.Hour, INFO, "refused"); err != ErrTimeout || time.Since(start) > time.Second {
+		t.Errorf("write behind an abandoned one returned %v after %v, want ErrTimeout at once", err, time.Since(start))
+	}
+	w.entered = nil
+	close(w.gate)
+	for atomic.LoadInt32(&logger.abandoned) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := logger.OutputTimeout(time.Second, INFO, "fast"); err != nil {
+		t.Errorf("free write returned %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], ": [INFO]: slow") || !strings.HasSuffix(lines[1], ": [INFO]: fast") {
+		t.Fatalf("got %q", w.String())
+	}
+	if !strings.HasPrefix(lines[1], "glog_test.go:") {
+		t.Errorf("caller %q, want the test", lines[1])
+	}
+
+	w = &gateWriter{gate: make(chan struct{}), entered: make(chan struct{}, 1)}
+	logger = newEx(w, "", 0)
+	logger.SetAsync(&AsyncConfig{})
+	logger.Info("written")
+	<-w.entered // the writer goroutine holds the logger until the gate opens
+	if err := logger.OutputTimeout(10*time.Millisecond, INFO, "abandoned"); err != ErrTimeout {
+		t.Errorf("blocked async write returned %v, want ErrTimeout", err)
+	}
+	close(w.gate)
+	logger.Info("after")
+	logger.SetAsync(nil)
+	if w.String() != "[INFO]: written\n[INFO]: after\n" {
+		t.Errorf("async got %q", w.String())
+	}
+}
+
+func TestSetFilter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetFilter(func(e Entry) bool { return !strings.Contains(e.Message, "/healthz") })
+	logger.Info("GET /healthz 200")
+	logger.Info("GET /users 200")
+	logger.OutputBytes(1, []byte("GET /healthz 200"))
+	logger.With("k", 1).Println("GET /healthz 200")
+	if buf.String() != "[INFO]: GET /users 200\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestSetReplaceInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetReplaceInvalidUTF8(true)
+	logger.Println("你好\xff\xfe测试")
+	logger.OutputBytes(1, []byte("raw \xc3\x28\n"))
+	logger.Println("你好，我是测试日志")
+	want := "你好�测试\nraw �(\n你好，我是测试日志\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestSetInternalErrorWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var main, diag bytes.Buffer
+	logger := NewEx(filepath.Join(dir, "testK.log"), "", 0, 1, 5)
+	logger.splitFileSize = 10
+	logger.SetOutput(&main)
+	logger.SetInternalErrorWriter(&diag)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	logger.Println("Hello, Glog!")
+	if main.String() != "Hello, Glog!\n" {
+		t.Errorf("main sink got %q", main.String())
+	}
+	if !strings.Contains(diag.String(), "glog: cannot rotate") {
+		t.Errorf("internal writer got %q", diag.String())
+	}
+	if _, err := logger.LastError(); err == nil || !strings.Contains(err.Error(), "cannot rotate") {
+		t.Errorf("LastError = %v", err)
+	}
+}
+
+type failingWriter struct {
+	fail bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestLastError(t *testing.T) {
+	w := &failingWriter{}
+	logger := newEx(w, "", 0)
+	logger.Println("ok")
+	if at, err := logger.LastError(); err != nil || !at.IsZero() {
+		t.Fatalf("LastError = %v, %v before any failure", err, at)
+	}
+	w.fail = true
+	clock := time.Date(2024, 5, 1, 10, 20, 37, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	logger.Println("lost")
+	at, err := logger.LastError()
+	if err == nil || err.Error() != "disk full" || !at.Equal(clock) {
+		t.Errorf("LastError = %v, %v after a failed write", err, at)
+	}
+	w.fail = false
+	logger.Println("ok again")
+	if at, err := logger.LastError(); err != nil || !at.IsZero() {
+		t.Errorf("LastError = %v, %v after a successful write", err, at)
+	}
+}
+
+func TestSetArchiveHandler(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testL.log")
+	var mu sync.Mutex
+	var shipped []string
+	logger := NewEx(filename, "", 0, 1, 5)
+	logger.splitFileSize = 10
+	logger.SetInternalErrorWriter(io.Discard)
+	logger.SetArchiveHandler(func(path string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		shipped = append(shipped, path)
+		if len(shipped) == 2 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+	logger.Println("Hello, Glog!")
+	logger.Println("Hello, Glog!")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(shipped) != 2 {
+		t.Fatalf("shipped %v, want 2 archives", shipped)
+	}
+	kept := 0
+	for _, path := range shipped {
+		if _, err := os.Stat(path); err == nil {
+			kept++
+		}
+	}
+	if kept != 1 {
+		t.Errorf("%d archives kept, want only the failed one", kept)
+	}
+
+	// a rotation racing Close starts no handler Close cannot wait for
+	logger.Rotate()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(shipped) != 2 {
+		t.Errorf("shipped %v after Close", shipped)
+	}
+}
+
+func TestArchiveHandlerIndexWrap(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testW.log")
+	logger := NewEx(filename, "", 0, 1, 1)
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	shipped := map[string]string{}
+	logger.SetArchiveHandler(func(path string) error {
+		<-gate // slower than the rotations
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		shipped[path] = string(data)
+		return nil
+	})
+	logger.SetInternalErrorWriter(io.Discard)
+	for _, line := range []string{"one", "two", "three"} {
+		logger.Println(line)
+		logger.Rotate()
+	}
+	close(gate)
+	logger.Close()
+	// the third rotation comes back round to the index of the first one
+	want := map[string]string{filename + ".0": "one\n", filename + ".1": "two\n", filename + ".0.1": "three\n"}
+	if !reflect.DeepEqual(shipped, want) {
+		t.Errorf("shipped %q, want %q", shipped, want)
+	}
+	for path := range want {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("%s kept after shipping", path)
+		}
+	}
+}
+
+func TestWrapErr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	err := logger.WrapErr(os.ErrNotExist, "loading %s", "app.conf")
+	if !errors.Is(err, os.ErrNotExist) || err.Error() != "loading app.conf: file does not exist" {
+		t.Errorf("WrapErr returned %v", err)
+	}
+	if buf.String() != "[ERROR]: loading app.conf: file does not exist\n" {
+		t.Errorf("got %q", buf.String())
+	}
+	if logger.WrapErr(nil, "nothing") != nil {
+		t.Error("WrapErr(nil) returned an error")
+	}
+}
+
+func TestNewFromFd(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	logger := NewFromFd(w.Fd(), "[Info] ", 0)
+	logger.Println("Hello!")
+	logger.Close()
+	w.Close()
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "[Info] Hello!\n" {
+		t.Errorf("read %q, %v", data, err)
+	}
+	if logger.BytesUntilRotate() != math.MaxUint64 {
+		t.Error("rotation enabled on a descriptor")
+	}
+}
+
+func TestSetOrderedTimestamps(t *testing.T) {
+	base := time.Date(2009, 1, 23, 1, 23, 23, 0, time.UTC)
+	var ticks int64
+	now = func() time.Time { return base.Add(time.Duration(atomic.AddInt64(&ticks, 1)) * time.Microsecond) }
+	defer func() { now = time.Now }()
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Ltime|Lmicroseconds|LUTC)
+	logger.SetOrderedTimestamps(true)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				logger.Println("tick")
+			}
+		}()
+	}
+	wg.Wait()
+	last := ""
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		stamp := strings.Fields(line)[0]
+		if stamp < last {
+			t.Fatalf("timestamp %s written after %s", stamp, last)
+		}
+		last = stamp
+	}
+}
+
+func logFromInner(logger *Logger) { logger.Warn("deep") }
+func logFromOuter(logger *Logger) { logFromInner(logger) }
+
+func TestSetHeaderFrames(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Lshortfile)
+	logger.SetHeaderFrames(3)
+	logFromOuter(logger)
+	chain := strings.SplitN(buf.String(), ": ", 2)[0]
+	frames := strings.Split(chain, "<-")
+	if len(frames) != 3 {
+		t.Fatalf("got %q, want 3 frames", buf.String())
+	}
+	for _, frame := range frames {
+		if !strings.HasPrefix(frame, "glog_test.go:") {
+			t.Errorf("unexpected frame %q in %q", frame, chain)
+		}
+	}
+	if frames[0] == frames[1] || frames[1] == frames[2] {
+		t.Errorf("repeated frames in %q", chain)
+	}
+
+	buf.Reset()
+	logger.SetHeaderFrames(1)
+	logFromOuter(logger)
+	if strings.Contains(buf.String(), "<-") {
+		t.Errorf("one frame got %q", buf.String())
+	}
+}
+
+func TestFlushString(t *testing.T) {
+	logger := newEx(&bytes.Buffer{}, "", 0)
+	logger.Println("one")
+	logger.Info("two")
+	if s, err := logger.FlushString(); s != "one\n[INFO]: two\n" || err != nil {
+		t.Errorf("FlushString() = %q, %v", s, err)
+	}
+	if s, err := logger.FlushString(); s != "" || err != nil {
+		t.Errorf("FlushString() after flushing = %q, %v", s, err)
+	}
+	if _, err := newEx(io.Discard, "", 0).FlushString(); err != ErrNotBuffer {
+		t.Errorf("FlushString() on a non buffer = %v", err)
+	}
+}
+
+func TestSetPrintLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetLevel(WARNING)
+	logger.Println("untagged")
+	logger.SetPrintLevel(INFO)
+	logger.Println("filtered")
+	logger.Printf("%s", "filtered")
+	logger.SetPrintLevel(ERROR)
+	logger.Print("tagged")
+	logger.With("k", 1).Println("child")
+	want := "untagged\n[ERROR]: tagged\n[ERROR]: child k=1\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestPackageSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	old := SwapOutput(&buf)
+	defer SwapOutput(old)
+	defer SetLevel(Level())
+	flags := Flags()
+	SetFlags(0)
+	defer SetFlags(flags)
+
+	SetLevel(WARNING)
+	if Level() != WARNING {
+		t.Errorf("Level() = %d, want WARNING", Level())
+	}
+	Debug("hidden")
+	Info("hidden")
+	Log(INFO).Msg("hidden")
+	Warn("shown")
+	Err("shown")
+	if buf.String() != "[WARN]: shown\n[ERROR]: shown\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestConcurrentFatal(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	var exits int
+	exit = func(int) {
+		exits++
+		time.Sleep(5 * time.Millisecond) // the others would write meanwhile
+		if lines := strings.Count(buf.String(), "\n"); lines != exits {
+			t.Errorf("%d lines written by exit %d, want only those of the exiting callers", lines, exits)
+		}
+	}
+	defer func() { exit = os.Exit }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			logger.Fatalf("fatal %d", i)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 4; i++ {
+		if !strings.Contains(buf.String(), "fatal "+string(rune('0'+i))+"\n") {
+			t.Errorf("line %d missing from %q", i, buf.String())
+		}
+	}
+}
+
+func TestSetLineSuffix(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetLineSuffix(func(e Entry) string {
+		if e.Level == DEBUG {
+			return ""
+		}
+		return " #" + strings.ToUpper(strings.TrimSpace(e.Message))
+	})
+	logger.Println("one")
+	logger.Info("two\n")
+	logger.Debug("three")
+	logger.OutputBytes(1, []byte("four\n"))
+	logger.With("k", 1).Info("five")
+	want := "one #ONE\n[INFO]: two #TWO\n[DEBUG]: three\nfour #FOUR\n[INFO]: five k=1 #FIVE\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestSetLineRotate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testM.log")
+	logger := NewEx(filename, "", 0, 1, 5)
+	logger.SetLineRotate(3)
+	for i := 0; i < 5; i++ {
+		logger.Println("line")
+	}
+	archived, err := os.ReadFile(filename + ".0")
+	if err != nil || string(archived) != "line\nline\nline\n" {
+		t.Errorf("archive holds %q, %v", archived, err)
+	}
+	if active, _ := os.ReadFile(filename); string(active) != "line\nline\n" {
+		t.Errorf("active file holds %q", active)
+	}
+	if _, err := os.Stat(filename + ".1"); err == nil {
+		t.Error("rotated before the third line")
+	}
+}
+
+func TestSetArchiveDir(t *testing.T) {
+	for _, crossDevice := range []bool{false, true} {
+		if crossDevice {
+			rename = func(string, string) error { return &os.LinkError{Op: "rename", Err: syscall.EXDEV} }
+		}
+		dir := t.TempDir()
+		archives := filepath.Join(dir, "archives")
+		filename := filepath.Join(dir, "testN.log")
+		logger := NewEx(filename, "", 0, 1, 5)
+		if err := logger.SetArchiveDir(archives); err != nil {
+			t.Fatal(err)
+		}
+		logger.Println("first")
+		if err := logger.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		logger.Println("second")
+		logger.Close()
+		rename = os.Rename
+
+		if data, err := os.ReadFile(filepath.Join(archives, "testN.log.0")); err != nil || string(data) != "first\n" {
+			t.Errorf("cross device %v: archive holds %q, %v", crossDevice, data, err)
+		}
+		if _, err := os.Stat(filename + ".0"); err == nil {
+			t.Errorf("cross device %v: archive left next to the active file", crossDevice)
+		}
+		if data, _ := os.ReadFile(filename); string(data) != "second\n" {
+			t.Errorf("cross device %v: active file holds %q", crossDevice, data)
+		}
+	}
+}
+
+func TestSetRemoveEmptyArchiveDir(t *testing.T) {
+	removed := func(dir string) bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				return true
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return false
+	}
+	for _, tt := range []struct {
+		name   string
+		grace  time.Duration
+		shared bool // the directory exists before SetArchiveDir
+		want   bool
+	}{
+		{"option not set", 0, false, false},
+		{"created by glog", 10 * time.Millisecond, false, true},
+		{"shared", 10 * time.Millisecond, true, false},
+	} {
+		dir := t.TempDir()
+		archives := filepath.Join(dir, "archives")
+		if tt.shared {
+			if err := os.Mkdir(archives, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		logger := NewEx(filepath.Join(dir, "testR.log"), "", 0, 1, 5)
+		if err := logger.SetArchiveDir(archives); err != nil {
+			t.Fatal(err)
+		}
+		logger.SetRemoveEmptyArchiveDir(tt.grace)
+		logger.SetArchiveHandler(func(string) error { return nil })
+		logger.Println("first")
+		if err := logger.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		logger.Close()
+		if got := removed(archives); got != tt.want {
+			t.Errorf("%s: archive directory removed %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// a directory holding something else is kept, and is created again by the next rotation
+	dir := t.TempDir()
+	archives := filepath.Join(dir, "archives")
+	logger := NewEx(filepath.Join(dir, "testR.log"), "", 0, 1, 5)
+	if err := logger.SetArchiveDir(archives); err != nil {
+		t.Fatal(err)
+	}
+	logger.SetRemoveEmptyArchiveDir(10 * time.Millisecond)
+	logger.SetArchiveHandler(func(string) error { return nil })
+	if err := os.WriteFile(filepath.Join(archives, "keep"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	logger.Println("first")
+	logger.Rotate()
+	logger.archives.Wait()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(archives); err != nil {
+		t.Fatalf("archive directory holding a file removed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(archives, "keep")); err != nil {
+		t.Fatal(err)
+	}
+	logger.Println("second")
+	logger.Rotate()
+	if !removed(archives) {
+		t.Fatal("emptied archive directory kept")
+	}
+	logger.SetArchiveHandler(nil)
+	logger.Println("third")
+	if err := logger.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Close()
+	if _, err := os.Stat(filepath.Join(archives, "testR.log.2")); err != nil {
+		t.Errorf("archive directory not created again: %v", err)
+	}
+}
+
+func TestRenameFallback(t *testing.T) {
+	locked := &os.LinkError{Op: "rename", Err: syscall.Errno(32)} // ERROR_SHARING_VIOLATION
+	defer func() { rename = os.Rename }()
+	for _, tt := range []struct {
+		name         string
+		failures     int // renames failing before one succeeds
+		retries      int
+		copyTruncate bool
+		archived     bool
+		diag         string
+	}{
+		{"retried", 2, 3, false, true, ""},
+		{"copy-truncate", 100, 2, true, true, "glog: cannot rename"},
+		{"no fallback", 100, 0, false, false, "glog: cannot archive the log file"},
+	} {
+		calls := 0
+		rename = func(src, dst string) error {
+			if calls++; calls <= tt.failures {
+				return locked
+			}
+			return os.Rename(src, dst)
+		}
+		filename := filepath.Join(t.TempDir(), "testV.log")
+		logger := NewEx(filename, "", 0, 1, 5)
+		var diag bytes.Buffer
+		logger.SetInternalErrorWriter(&diag)
+		logger.SetRenameRetry(tt.retries, time.Millisecond)
+		logger.SetCopyTruncate(tt.copyTruncate)
+		logger.Println("first")
+		logger.Rotate()
+		logger.Println("second")
+		logger.Close()
+
+		if want := tt.retries + 1; tt.failures > tt.retries && calls != want {
+			t.Errorf("%s: %d renames, want %d", tt.name, calls, want)
+		}
+		archive, err := os.ReadFile(filename + ".0")
+		if tt.archived && (err != nil || string(archive) != "first\n") {
+			t.Errorf("%s: archive holds %q, %v", tt.name, archive, err)
+		}
+		if !tt.archived && err == nil {
+			t.Errorf("%s: archived %q", tt.name, archive)
+		}
+		want := "second\n"
+		if !tt.archived {
+			want = "first\nsecond\n"
+		}
+		if data, _ := os.ReadFile(filename); string(data) != want {
+			t.Errorf("%s: active file holds %q, want %q", tt.name, data, want)
+		}
+		if tt.diag == "" && diag.Len() != 0 || !strings.HasPrefix(diag.String(), tt.diag) {
+			t.Errorf("%s: reported %q", tt.name, diag.String())
+		}
+	}
+}
+
+func TestOversizedLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testP.log")
+	logger := NewEx(filename, "", 0, 1, 5)
+	logger.splitFileSize = 10
+	logger.Println("ab")
+	logger.Println(strings.Repeat("x", 24))
+	logger.Println("cd")
+	logger.Close()
+	want := map[string]string{
+		filename + ".0": "ab\n",
+		filename + ".1": strings.Repeat("x", 24) + "\n",
+		filename:        "cd\n",
+	}
+	for path, content := range want {
+		if data, err := os.ReadFile(path); err != nil || string(data) != content {
+			t.Errorf("%s holds %q, %v, want %q", filepath.Base(path), data, err, content)
+		}
+	}
+}
+
+func TestSplitBytes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testB.log")
+	logger := NewExBytes(filename, "", 0, 4096, 5)
+	line := strings.Repeat("x", 1023) // 1024 bytes with the newline
+	for i := 0; i < 5; i++ {
+		logger.Println(line)
+	}
+	if got := logger.BytesUntilRotate(); got != 3072 {
+		t.Errorf("BytesUntilRotate %d after one line in the new file, want 3072", got)
+	}
+	logger.SetSplitBytes(2048)
+	logger.Println(line)
+	logger.Close()
+	for path, size := range map[string]int64{filename + ".0": 4096, filename + ".1": 2048, filename: 0} {
+		if fi, err := os.Stat(path); err != nil || fi.Size() != size {
+			t.Errorf("%s: %v, %v, want %d bytes", filepath.Base(path), fi, err, size)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testR.log")
+	logger := NewExBytes(filename, "[x] ", Lshortfile, 1<<20, 2)
+	defer logger.Close()
+	logger.Println("before")
+	var other bytes.Buffer
+	logger.SetOutput(&other)
+	logger.SetLevel(ERROR)
+	logger.SetPrintLevel(WARNING)
+	logger.SetHeaderSeparator("|")
+	logger.SetFormatter(&JSONFormatter{})
+	logger.SetFilter(func(Entry) bool { return false })
+	logger.SetLineSuffix(func(Entry) string { return "!" })
+	logger.SetLevelSampling(map[int]int{INFO: 10})
+	logger.SetBurstSampler(1, 0)
+	logger.SetLineRotate(3)
+	logger.SetSkipEmpty(true)
+	logger.AddLineCallback(func([]byte) { t.Error("line callback kept") })
+	logger.SetAsync(&AsyncConfig{})
+	logger.Reset()
+
+	data, _ := os.ReadFile(filename)
+	written := logger.writtenSize
+	if len(data) == 0 || written != uint64(len(data)) {
+		t.Fatalf("written size %d after Reset, file holds %q", written, data)
+	}
+	if logger.Flags() != LstdFlags || logger.Prefix() != "" || logger.Level() != DEBUG || logger.printLevelOf() != NOLEVEL {
+		t.Errorf("flags %d, prefix %q, level %d, print level %d", logger.Flags(), logger.Prefix(), logger.Level(), logger.printLevelOf())
+	}
+	if logger.splitFileSize != SPLIT_FILE_SIZE*1024*1024 || logger.totalRotateSplit != TOTAL_ROTATE_SPLIT || logger.lineRotate != 0 {
+		t.Errorf("split size %d, count %d, line rotate %d", logger.splitFileSize, logger.totalRotateSplit, logger.lineRotate)
+	}
+	logger.SetFlags(0)
+	for i := 0; i < 3; i++ {
+		logger.Info("same")
+	}
+	logger.Println("")
+	want := strings.Repeat("[INFO]: same\n", 3) + "\n"
+	if got, _ := os.ReadFile(filename); string(got) != string(data)+want || other.Len() != 0 {
+		t.Errorf("file holds %q, other output %q, want %q", got, other.String(), want)
+	}
+	if logger.writtenSize != written+uint64(len(want)) {
+		t.Errorf("written size %d, want %d", logger.writtenSize, written+uint64(len(want)))
+	}
+}
+
+/*flushHandler counts the calls of Flush.*/
+type flushHandler struct {
+	entries, flushes int
+}
+
+func (h *flushHandler) Handle(e *Entry) error {
+	h.entries++
+	return nil
+}
+
+func (h *flushHandler) Flush() error {
+	h.flushes++
+	return nil
+}
+
+func TestOnShutdown(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testS.log")
+	logger := New(filename, "", 0)
+	logger.SetAsync(&AsyncConfig{})
+	for i := 0; i < 100; i++ {
+		logger.Println("line")
+	}
+	shutdown := logger.OnShutdown()
+	if err := shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(); err != nil {
+		t.Errorf("second call: %v, want the nil of the first one", err)
+	}
+	if data, _ := os.ReadFile(filename); strings.Count(string(data), "line\n") != 100 {
+		t.Errorf("%d lines written, want 100", strings.Count(string(data), "line\n"))
+	}
+	if _, err := logger.fileHandle.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("write after shutdown: %v, want the file closed", err)
+	}
+
+	h := &flushHandler{}
+	logger = newEx(io.Discard, "", 0)
+	logger.SetHandler(h)
+	logger.Info("entry")
+	shutdown = logger.OnShutdown()
+	shutdown()
+	shutdown()
+	if h.entries != 1 || h.flushes != 1 {
+		t.Errorf("handler got %d entries and %d flushes, want 1 and 1", h.entries, h.flushes)
+	}
+}
+
+func TestNewBare(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testT.log")
+	logger := NewBare(filename)
+	logger.Info("request %d done", 7)
+	logger.Err("disk full")
+	logger.Println("plain")
+	logger.Close()
+	if data, _ := os.ReadFile(filename); string(data) != "request 7 done\ndisk full\nplain\n" {
+		t.Errorf("got %q", data)
+	}
+
+	var buf bytes.Buffer
+	logger = newEx(&buf, "[app] ", LstdFlags|Lshortfile|Lpid)
+	logger.SetBare(true)
+	logger.Warn("exactly this")
+	if buf.String() != "exactly this\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestSetPrefixFunc(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[static] ", 0)
+	n := 0
+	logger.SetPrefixFunc(func() string {
+		n++
+		if n == 3 {
+			return ""
+		}
+		return "[req-" + strconv.Itoa(n) + "] "
+	})
+	logger.Info("a")
+	logger.With("k", 1).Info("b")
+	logger.Info("c")
+	logger.Println("d")
+	logger.LogEntry(Entry{Prefix: "[own] ", Level: NOLEVEL, Message: "e"})
+	logger.SetPrefixFunc(nil)
+	logger.Info("f")
+	want := "[req-1] [INFO]: a\n[req-2] [INFO]: b k=1\n[static] [INFO]: c\n[req-4] d\n[own] e\n[static] [INFO]: f\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestInfoNoCaller(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", Lshortfile|Lmethodonly|Lpackage)
+	logger.InfoNoCaller("hot %d", 1)
+	logger.DebugNoCaller("hot")
+	logger.WarnNoCaller("hot")
+	logger.ErrNoCaller("hot")
+	if want := "[INFO]: hot 1\n[DEBUG]: hot\n[WARN]: hot\n[ERROR]: hot\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	logger.Info("cold")
+	if !strings.HasPrefix(buf.String(), "glog_test.go:") {
+		t.Errorf("Info lost its caller: %q", buf.String())
+	}
+}
+
+func BenchmarkInfoNoCaller(b *testing.B) {
+	logger := newEx(io.Discard, "[Info] ", LstdFlags|Lshortfile)
+	b.Run("Info", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("abcdefghijklmnopqrstuvwxyz0123456789")
+		}
+	})
+	b.Run("InfoNoCaller", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.InfoNoCaller("abcdefghijklmnopqrstuvwxyz0123456789")
+		}
+	})
+}
+
+func TestSetFilename(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "testT.log"), filepath.Join(dir, "testT-2024-05-01.log")
+	os.WriteFile(second, []byte("earlier\n"), 0644)
+	logger := NewExBytes(first, "", 0, 32, 5)
+	logger.SetAsync(&AsyncConfig{})
+	logger.Println("before")
+	if err := logger.SetFilename(second); err != nil {
+		t.Fatal(err)
+	}
+	logger.Println("after")
+	if err := logger.SetFilename(filepath.Join(dir, "missing", "x.log")); err == nil {
+		t.Error("SetFilename to a missing directory succeeded")
+	}
+	logger.Println("still there")
+	logger.Println("rotates the new file")
+	logger.Println("fresh")
+	logger.Close()
+	if data, _ := os.ReadFile(first); string(data) != "before\n" {
+		t.Errorf("old file holds %q", data)
+	}
+	if data, _ := os.ReadFile(second + ".0"); string(data) != "earlier\nafter\nstill there\nrotates the new file\n" {
+		t.Errorf("archive of the new file holds %q", data)
+	}
+	if data, _ := os.ReadFile(second); string(data) != "fresh\n" {
+		t.Errorf("new file holds %q", data)
+	}
+	if err := newEx(io.Discard, "", 0).SetFilename(second); err != ErrNotFile {
+		t.Errorf("got %v, want ErrNotFile", err)
+	}
+}
+
+func TestSetMaxMessageSize(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[app] ", 0)
+	logger.SetMaxMessageSize(10)
+	logger.Info("0123456789 and more")
+	logger.SetTruncationSummary(true)
+	big := strings.Repeat("é", 100) // 200 bytes
+	logger.Warn("%s", big)
+	logger.Info("short")
+	logger.OutputBytes(2, []byte("bytes past the cap\n"))
+	want := "[app] [INFO]: 0123456789\n" +
+		"[app] [WARN]: ééééé\n" +
+		"[app] [INFO]: truncated message: 200 bytes, starts with \"" + strings.Repeat("é", 32) + "\"\n" +
+		"[app] [INFO]: short\n" +
+		"[app] bytes past\n" +
+		"[app] [INFO]: truncated message: 19 bytes, starts with \"bytes past the cap\\n\"\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestOutputBatch(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testU.log")
+	logger := NewExBytes(filename, "", 0, 16, 5)
+	logger.SetLevel(INFO)
+	entries := make([]Entry, 0, 10)
+	for i := 0; i < 10; i++ {
+		entries = append(entries, Entry{Level: INFO, Message: "entry " + strconv.Itoa(i)})
+	}
+	entries[3].Level = DEBUG
+	if err := logger.OutputBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+	logger.Println("after")
+	logger.Close()
+	// the batch goes to a single file, rotated once after it
+	data, err := os.ReadFile(filename + ".0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	for i := 0; i < 10; i++ {
+		if i != 3 {
+			want.WriteString("[INFO]: entry " + strconv.Itoa(i) + "\n")
+		}
+	}
+	if string(data) != want.String() {
+		t.Errorf("archive holds %q\nwant %q", data, want.String())
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "after\n" {
+		t.Errorf("active file holds %q", data)
+	}
+
+	// in async mode the writer goroutine rotates between the entries
+	filename = filepath.Join(t.TempDir(), "testU.log")
+	logger = NewExBytes(filename, "", 0, 16, 5)
+	logger.SetAsync(&AsyncConfig{})
+	if err := logger.OutputBatch(entries[:3]); err != nil {
+		t.Fatal(err)
+	}
+	logger.SetAsync(nil)
+	logger.Close()
+	for i := 0; i < 3; i++ {
+		want := "[INFO]: entry " + strconv.Itoa(i) + "\n"
+		if data, _ := os.ReadFile(filename + "." + strconv.Itoa(i)); string(data) != want {
+			t.Errorf("async archive %d holds %q, want %q", i, data, want)
+		}
+	}
+}
+
+func BenchmarkOutputBatch(b *testing.B) {
+	entries := make([]Entry, 100)
+	for i := range entries {
+		entries[i] = Entry{Time: benchEntry.Time, Level: INFO, Message: benchEntry.Message}
+	}
+	logger := newEx(io.Discard, "", LstdFlags)
+	b.Run("LogEntry", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, e := range entries {
+				logger.LogEntry(e)
+			}
+		}
+	})
+	b.Run("OutputBatch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.OutputBatch(entries)
+		}
+	})
+}
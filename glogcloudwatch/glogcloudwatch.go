@@ -0,0 +1,277 @@
+/*
+Package glogcloudwatch ships the entries of a glog.Logger to a CloudWatch
+Logs stream with github.com/aws/aws-sdk-go-v2:
+
+	sink, err := glogcloudwatch.NewCloudWatch("my-group", "my-stream")
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+	logger.SetHandler(sink)
+
+The log group and stream must exist. It is a module of its own, so that glog
+does not pull in the AWS SDK.
+*/
+package glogcloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/zydp/glog"
+)
+
+/*Limits of a PutLogEvents call, as set by CloudWatch Logs.*/
+const (
+	MAX_BATCH_EVENTS = 10000       // events per call
+	MAX_BATCH_BYTES  = 1048576     // bytes per call, each event counting EVENT_OVERHEAD bytes more than its message
+	MAX_EVENT_BYTES  = 262144 - 26 // bytes of a message, longer ones are truncated on a character boundary
+	EVENT_OVERHEAD   = 26
+)
+
+/*Defaults of a Sink.*/
+const (
+	FLUSH_INTERVAL = 5 * time.Second        // longest wait of a partial batch
+	MAX_RETRIES    = 5                      // retries of a throttled call
+	RETRY_DELAY    = 200 * time.Millisecond // wait before the first retry, doubled on each one
+)
+
+/*A Client puts events to CloudWatch Logs, *cloudwatchlogs.Client is one; tests use a mock.*/
+type Client interface {
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+/*
+A Sink is a glog.Handler putting each entry, formatted by Formatter, as an
+event of a CloudWatch Logs stream. The events are batched and put from a
+goroutine of the sink, once BatchSize of them or MAX_BATCH_BYTES are pending
+or FlushInterval has passed, so logging does not wait for CloudWatch; an entry
+at FATAL is put before Handle returns. The batches keep to the limits of
+CloudWatch, with their events in chronological order, and the sequence token
+of the stream is passed along and taken from the InvalidSequenceToken errors.
+A throttled call is retried up to MaxRetries times, waiting RetryDelay then
+twice as long each time. The entries go to the sink instead of the output, so
+the size based rotation of the logger does not apply. The settings have to be
+made before the first entry, afterwards a Sink is safe for concurrent use.
+*/
+type Sink struct {
+	Formatter     glog.Formatter // renders the events, a JSONFormatter when nil
+	BatchSize     int            // events per call, MAX_BATCH_EVENTS when zero
+	FlushInterval time.Duration  // FLUSH_INTERVAL when zero
+	MaxRetries    int            // MAX_RETRIES when zero, less than zero does not retry
+	RetryDelay    time.Duration  // RETRY_DELAY when zero
+	OnError       func(error)    // receives the errors of the batches put from the goroutine, nil writes them to os.Stderr
+
+	mu           sync.Mutex
+	c            Client
+	group        string
+	stream       string
+	pending      []types.InputLogEvent
+	pendingBytes int
+	buf          []byte
+	kick         chan struct{} // wakes the delivery goroutine for a full batch
+	stop         chan struct{}
+	stopped      chan struct{}
+	sendMu       sync.Mutex // orders the calls and protects token
+	token        *string    // sequence token of the next call
+	started      bool       // run was started by the first entry, once the settings are made
+	closed       bool
+}
+
+/*NewCloudWatch returns a Sink putting to the stream of the log group with the default AWS configuration of the environment.*/
+func NewCloudWatch(group, stream string) (*Sink, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(cloudwatchlogs.NewFromConfig(cfg), group, stream), nil
+}
+
+/*NewSink returns a Sink putting to the stream of the log group through c.*/
+func NewSink(c Client, group, stream string) *Sink {
+	return &Sink{c: c, group: group, stream: stream, kick: make(chan struct{}, 1), stop: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+/*Handle implements glog.Handler.*/
+func (s *Sink) Handle(e *glog.Entry) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	if !s.started {
+		s.started = true
+		go s.run(s.FlushInterval)
+	}
+	f := s.Formatter
+	if f == nil {
+		f = &glog.JSONFormatter{}
+	}
+	s.buf = f.Format(s.buf[:0], e)
+	msg := s.buf
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	if len(msg) > MAX_EVENT_BYTES {
+		n := MAX_EVENT_BYTES
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n-- // do not split a character
+		}
+		msg = msg[:n]
+	}
+	s.pending = append(s.pending, types.InputLogEvent{Message: aws.String(string(msg)), Timestamp: aws.Int64(e.Time.UnixMilli())})
+	s.pendingBytes += len(msg) + EVENT_OVERHEAD
+	full := len(s.pending) >= s.batchSize() || s.pendingBytes >= MAX_BATCH_BYTES
+	s.mu.Unlock()
+	if e.Level == glog.FATAL {
+		return s.Flush()
+	}
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *Sink) batchSize() int {
+	if s.BatchSize <= 0 || s.BatchSize > MAX_BATCH_EVENTS {
+		return MAX_BATCH_EVENTS
+	}
+	return s.BatchSize
+}
+
+/*run puts the pending events on a full batch or each flush interval, until Close.*/
+func (s *Sink) run(interval time.Duration) {
+	defer close(s.stopped)
+	if interval <= 0 {
+		interval = FLUSH_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.kick:
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		if err := s.Flush(); err != nil {
+			s.report(err)
+		}
+	}
+}
+
+func (s *Sink) report(err error) {
+	s.mu.Lock()
+	onError := s.OnError
+	s.mu.Unlock()
+	if onError != nil {
+		onError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "glog: cannot put to CloudWatch Logs: %v\n", err)
+}
+
+/*Flush puts the pending events, in batches within the limits of CloudWatch, and waits for it. The events from a failed batch on are dropped.*/
+func (s *Sink) Flush() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.mu.Lock()
+	events := s.pending
+	s.pending = nil
+	s.pendingBytes = 0
+	batch := s.batchSize()
+	s.mu.Unlock()
+	sort.SliceStable(events, func(i, j int) bool { return *events[i].Timestamp < *events[j].Timestamp })
+	for len(events) > 0 {
+		n, size := 0, 0
+		for n < len(events) && n < batch {
+			eventSize := len(*events[n].Message) + EVENT_OVERHEAD
+			if n > 0 && size+eventSize > MAX_BATCH_BYTES {
+				break
+			}
+			size += eventSize
+			n++
+		}
+		if err := s.put(events[:n]); err != nil {
+			return err
+		}
+		events = events[n:]
+	}
+	return nil
+}
+
+/*put makes the PutLogEvents call of a batch, retrying it when throttled or given another sequence token, s.sendMu must be held.*/
+func (s *Sink) put(events []types.InputLogEvent) error {
+	retries := s.MaxRetries
+	if retries == 0 {
+		retries = MAX_RETRIES
+	}
+	delay := s.RetryDelay
+	if delay <= 0 {
+		delay = RETRY_DELAY
+	}
+	for attempt := 0; ; attempt++ {
+		out, err := s.c.PutLogEvents(context.Background(), &cloudwatchlogs.PutLogEventsInput{
+			LogEvents:     events,
+			LogGroupName:  aws.String(s.group),
+			LogStreamName: aws.String(s.stream),
+			SequenceToken: s.token,
+		})
+		if err == nil {
+			if out != nil {
+				s.token = out.NextSequenceToken
+			}
+			return nil
+		}
+		var accepted *types.DataAlreadyAcceptedException
+		if errors.As(err, &accepted) {
+			s.token = accepted.ExpectedSequenceToken
+			return nil
+		}
+		if attempt >= retries {
+			return err
+		}
+		var invalid *types.InvalidSequenceTokenException
+		var apiErr smithy.APIError
+		switch {
+		case errors.As(err, &invalid):
+			s.token = invalid.ExpectedSequenceToken
+		case errors.As(err, &apiErr) && apiErr.ErrorCode() == "ThrottlingException":
+			time.Sleep(delay)
+			delay *= 2
+		default:
+			return err
+		}
+	}
+}
+
+/*Close puts the pending events, the entries handled afterwards are refused.*/
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		close(s.stop)
+		<-s.stopped
+	}
+	return s.Flush()
+}
@@ -0,0 +1,113 @@
+package glogcloudwatch
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/zydp/glog"
+)
+
+/*mockClient records the calls it is given and answers them with errs first, then with the next sequence token.*/
+type mockClient struct {
+	mu    sync.Mutex
+	calls []cloudwatchlogs.PutLogEventsInput
+	errs  []error
+}
+
+func (c *mockClient) PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, *params)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String("t" + string(rune('0'+len(c.calls))))}, nil
+}
+
+func TestSink(t *testing.T) {
+	c := &mockClient{}
+	sink := NewSink(c, "group", "stream")
+	sink.Formatter = &glog.TextFormatter{}
+	sink.BatchSize = 2
+	logger := glog.New(filepath.Join(t.TempDir(), "cloudwatch.log"), "", 0)
+	defer logger.Close()
+	logger.SetHandler(sink)
+
+	logger.Info("one")
+	logger.Info("two")
+	logger.Warn("three")
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.calls) != 2 || len(c.calls[0].LogEvents) != 2 || len(c.calls[1].LogEvents) != 1 {
+		t.Fatalf("got %d calls, want batches of 2 and 1", len(c.calls))
+	}
+	if aws.ToString(c.calls[0].LogGroupName) != "group" || aws.ToString(c.calls[0].LogStreamName) != "stream" {
+		t.Errorf("put to %s/%s", aws.ToString(c.calls[0].LogGroupName), aws.ToString(c.calls[0].LogStreamName))
+	}
+	if c.calls[0].SequenceToken != nil || aws.ToString(c.calls[1].SequenceToken) != "t1" {
+		t.Errorf("sequence tokens %v and %q, want none then t1", c.calls[0].SequenceToken, aws.ToString(c.calls[1].SequenceToken))
+	}
+	if got := aws.ToString(c.calls[1].LogEvents[0].Message); got != "[WARN]: three" {
+		t.Errorf("got message %q", got)
+	}
+	if err := sink.Handle(&glog.Entry{}); err == nil {
+		t.Error("Handle after Close succeeded")
+	}
+}
+
+func TestSinkRetries(t *testing.T) {
+	c := &mockClient{errs: []error{
+		&smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"},
+		&types.InvalidSequenceTokenException{ExpectedSequenceToken: aws.String("t9")},
+	}}
+	sink := NewSink(c, "group", "stream")
+	sink.RetryDelay = time.Millisecond
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	sink.Handle(&glog.Entry{Time: base.Add(time.Second), Level: glog.INFO, Message: "later"})
+	sink.Handle(&glog.Entry{Time: base, Level: glog.INFO, Message: "earlier"})
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.calls) != 3 || aws.ToString(c.calls[2].SequenceToken) != "t9" {
+		t.Fatalf("got %d calls, want the third with the expected token t9", len(c.calls))
+	}
+	events := c.calls[2].LogEvents
+	if !strings.Contains(aws.ToString(events[0].Message), "earlier") || aws.ToInt64(events[0].Timestamp) != base.UnixMilli() {
+		t.Errorf("the events are not in chronological order: %q first", aws.ToString(events[0].Message))
+	}
+
+	c.errs = []error{&smithy.GenericAPIError{Code: "AccessDeniedException"}}
+	sink.Handle(&glog.Entry{Level: glog.INFO, Message: "denied"})
+	if err := sink.Flush(); err == nil {
+		t.Error("an error other than throttling was retried")
+	}
+	sink.Close()
+}
+
+func TestSinkBatchBytes(t *testing.T) {
+	c := &mockClient{}
+	sink := NewSink(c, "group", "stream")
+	sink.Formatter = &glog.TextFormatter{}
+	huge := strings.Repeat("x", 300*1024)
+	for i := 0; i < 5; i++ {
+		sink.Handle(&glog.Entry{Level: glog.NOLEVEL, Message: huge})
+	}
+	sink.Close()
+	if len(c.calls) != 2 || len(c.calls[0].LogEvents) != 4 || len(c.calls[1].LogEvents) != 1 {
+		t.Fatalf("got %d calls, want batches of 4 and 1 events", len(c.calls))
+	}
+	if n := len(aws.ToString(c.calls[0].LogEvents[0].Message)); n != MAX_EVENT_BYTES {
+		t.Errorf("message of %d bytes, want it truncated to %d", n, MAX_EVENT_BYTES)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Package glogeventlog writes the entries of a glog.Logger to the Windows
+Event Log with golang.org/x/sys/windows/svc/eventlog, as Information,
+Warning or Error events depending on their level:
+
+	sink, err := glogeventlog.NewEventLog("myservice")
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+	logger.SetHandler(sink)
+
+NewEventLog is only available on Windows, the source must have been
+registered, e.g. with eventlog.InstallAsEventCreate, by the installer of the
+service. It is a module of its own, so that glog does not pull in x/sys.
+*/
+package glogeventlog
+
+import (
+	"sync"
+
+	"github.com/zydp/glog"
+)
+
+/*Types of the events, as the EVENTLOG_*_TYPE values of Windows.*/
+const (
+	ERROR_TYPE   = 1
+	WARNING_TYPE = 2
+	INFO_TYPE    = 4
+)
+
+/*EVENT_ID is the identifier of the events of a Sink whose EventID is zero.*/
+const EVENT_ID = 1
+
+/*An EventWriter reports events to the Event Log, *eventlog.Log is one; tests use a mock.*/
+type EventWriter interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+/*
+A Sink is a glog.Handler reporting each entry, formatted by Formatter, as an
+event of the type EventType gives for its level. The Event Log records the
+time and the source of the events, so the default format is the bare message
+followed by the fields. The entries go to the sink instead of the output, so
+the size based rotation of the logger does not apply. The settings have to be
+made before the first entry, afterwards a Sink is safe for concurrent use.
+*/
+type Sink struct {
+	Formatter glog.Formatter // renders the events, a bare TextFormatter when nil
+	EventID   uint32         // identifier of the events, EVENT_ID when zero
+
+	mu  sync.Mutex
+	w   EventWriter
+	buf []byte
+}
+
+/*NewSink returns a Sink reporting through w, which it closes on Close.*/
+func NewSink(w EventWriter) *Sink {
+	return &Sink{w: w}
+}
+
+/*
+EventType returns the type of the event of an entry at level: ERROR_TYPE from
+ERROR on, WARNING_TYPE for WARNING and INFO_TYPE for the levels below and the
+entries without level.
+*/
+func EventType(level int) uint32 {
+	switch {
+	case level >= glog.ERROR:
+		return ERROR_TYPE
+	case level == glog.WARNING:
+		return WARNING_TYPE
+	}
+	return INFO_TYPE
+}
+
+/*Handle implements glog.Handler.*/
+func (s *Sink) Handle(e *glog.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.Formatter
+	if f == nil {
+		f = &glog.TextFormatter{Bare: true}
+	}
+	s.buf = f.Format(s.buf[:0], e)
+	msg := s.buf
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	eid := s.EventID
+	if eid == 0 {
+		eid = EVENT_ID
+	}
+	switch EventType(e.Level) {
+	case ERROR_TYPE:
+		return s.w.Error(eid, string(msg))
+	case WARNING_TYPE:
+		return s.w.Warning(eid, string(msg))
+	}
+	return s.w.Info(eid, string(msg))
+}
+
+/*Close closes the event writer.*/
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Close()
+}
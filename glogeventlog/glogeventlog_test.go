@@ -0,0 +1,87 @@
+package glogeventlog
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zydp/glog"
+)
+
+type event struct {
+	typ uint32
+	eid uint32
+	msg string
+}
+
+/*mockWriter records the events it is given.*/
+type mockWriter struct {
+	events []event
+	closed bool
+}
+
+func (w *mockWriter) Info(eid uint32, msg string) error {
+	w.events = append(w.events, event{INFO_TYPE, eid, msg})
+	return nil
+}
+
+func (w *mockWriter) Warning(eid uint32, msg string) error {
+	w.events = append(w.events, event{WARNING_TYPE, eid, msg})
+	return nil
+}
+
+func (w *mockWriter) Error(eid uint32, msg string) error {
+	w.events = append(w.events, event{ERROR_TYPE, eid, msg})
+	return nil
+}
+
+func (w *mockWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestEventType(t *testing.T) {
+	for level, want := range map[int]uint32{
+		glog.NOLEVEL: INFO_TYPE,
+		glog.DEBUG:   INFO_TYPE,
+		glog.INFO:    INFO_TYPE,
+		glog.WARNING: WARNING_TYPE,
+		glog.ERROR:   ERROR_TYPE,
+		glog.FATAL:   ERROR_TYPE,
+	} {
+		if got := EventType(level); got != want {
+			t.Errorf("level %d: got type %d, want %d", level, got, want)
+		}
+	}
+}
+
+func TestSink(t *testing.T) {
+	w := &mockWriter{}
+	sink := NewSink(w)
+	logger := glog.New(filepath.Join(t.TempDir(), "eventlog.log"), "", glog.LstdFlags)
+	defer logger.Close()
+	logger.SetHandler(sink)
+
+	logger.Debug("starting")
+	logger.Warn("disk at %d%%", 91)
+	logger.With("path", "/var").Err("disk full")
+	sink.EventID = 7
+	logger.Println("plain")
+	sink.Close()
+	want := []event{
+		{INFO_TYPE, EVENT_ID, "starting"},
+		{WARNING_TYPE, EVENT_ID, "disk at 91%"},
+		{ERROR_TYPE, EVENT_ID, "disk full path=/var"},
+		{INFO_TYPE, 7, "plain"},
+	}
+	if len(w.events) != len(want) {
+		t.Fatalf("got %v, want %v", w.events, want)
+	}
+	for i := range want {
+		if w.events[i] != want[i] {
+			t.Errorf("event %d: got %v, want %v", i, w.events[i], want[i])
+		}
+	}
+	if !w.closed {
+		t.Error("Close did not close the writer")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package glogeventlog
+
+import "golang.org/x/sys/windows/svc/eventlog"
+
+/*NewEventLog returns a Sink reporting to the Event Log as source, which must be registered.*/
+func NewEventLog(source string) (*Sink, error) {
+	w, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(w), nil
+}
@@ -0,0 +1,7 @@
+module github.com/zydp/glog/glogeventlog
+
+go 1.21
+
+require github.com/zydp/glog v0.0.0-20261014153822-d3637163128b
+
+require golang.org/x/sys v0.15.0
@@ -0,0 +1,204 @@
+/*
+Package glogkafka produces the entries of a glog.Logger to a Kafka topic
+with github.com/segmentio/kafka-go:
+
+	sink := glogkafka.NewKafka([]string{"kafka:9092"}, "logs")
+	defer sink.Close()
+	logger.SetHandler(sink)
+
+It is a module of its own, so that glog does not pull in a Kafka client.
+*/
+package glogkafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/zydp/glog"
+)
+
+/*Defaults of a Sink.*/
+const (
+	BATCH_SIZE     = 100         // messages per produce call
+	FLUSH_INTERVAL = time.Second // longest wait of a partial batch
+)
+
+/*A Producer delivers messages to Kafka, *kafka.Writer is one; tests use a mock.*/
+type Producer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+/*
+A Sink is a glog.Handler producing each entry, formatted by Formatter, as a
+Kafka message, keyed by the value of the field KeyField when it is set so
+that the entries of a key land on the same partition. The messages are
+batched and delivered from a goroutine of the sink, once BatchSize of them
+are pending or FlushInterval has passed, so logging does not wait for the
+brokers; an entry at FATAL is delivered before Handle returns. The entries
+go to the sink instead of the output, so the size based rotation of the
+logger does not apply. The settings have to be made before the first entry,
+afterwards a Sink is safe for concurrent use.
+*/
+type Sink struct {
+	Formatter     glog.Formatter // renders the message values, a JSONFormatter when nil
+	KeyField      string         // field whose value keys the messages, "" leaves them unkeyed
+	BatchSize     int            // BATCH_SIZE when zero
+	FlushInterval time.Duration  // FLUSH_INTERVAL when zero
+	OnError       func(error)    // receives the delivery errors, nil writes them to os.Stderr
+
+	mu      sync.Mutex
+	p       Producer
+	pending []kafka.Message
+	buf     []byte
+	kick    chan struct{} // wakes the delivery goroutine for a full batch
+	stop    chan struct{}
+	stopped chan struct{}
+	sendMu  sync.Mutex // orders the produce calls
+	started bool       // run was started by the first entry, once the settings are made
+	closed  bool
+}
+
+/*NewKafka returns a Sink producing to topic through the brokers, e.g. "kafka:9092".*/
+func NewKafka(brokers []string, topic string) *Sink {
+	return NewSink(&kafka.Writer{Addr: kafka.TCP(brokers...), Topic: topic, Balancer: &kafka.Hash{}})
+}
+
+/*NewSink returns a Sink delivering through p, which it closes on Close.*/
+func NewSink(p Producer) *Sink {
+	return &Sink{p: p, kick: make(chan struct{}, 1), stop: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+/*Handle implements glog.Handler.*/
+func (s *Sink) Handle(e *glog.Entry) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	if !s.started {
+		s.started = true
+		go s.run(s.FlushInterval)
+	}
+	f := s.Formatter
+	if f == nil {
+		f = &glog.JSONFormatter{}
+	}
+	s.buf = f.Format(s.buf[:0], e)
+	value := s.buf
+	if n := len(value); n > 0 && value[n-1] == '\n' {
+		value = value[:n-1]
+	}
+	msg := kafka.Message{Value: append([]byte(nil), value...), Time: e.Time}
+	if s.KeyField != "" {
+		for _, field := range e.Fields {
+			if field.Key == s.KeyField {
+				msg.Key = []byte(fmt.Sprint(field.Value))
+			}
+		}
+	}
+	s.pending = append(s.pending, msg)
+	full := len(s.pending) >= s.batchSize()
+	s.mu.Unlock()
+	if e.Level == glog.FATAL {
+		return s.Flush()
+	}
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *Sink) batchSize() int {
+	if s.BatchSize <= 0 {
+		return BATCH_SIZE
+	}
+	return s.BatchSize
+}
+
+/*run delivers the pending messages on a full batch or each flush interval, until Close.*/
+func (s *Sink) run(interval time.Duration) {
+	defer close(s.stopped)
+	if interval <= 0 {
+		interval = FLUSH_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.kick:
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		if err := s.Flush(); err != nil {
+			s.report(err)
+		}
+	}
+}
+
+func (s *Sink) report(err error) {
+	s.mu.Lock()
+	onError := s.OnError
+	s.mu.Unlock()
+	if onError != nil {
+		onError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "glog: cannot produce to Kafka: %v\n", err)
+}
+
+/*
+Flush delivers the pending messages, in batches of BatchSize, and waits for
+the brokers. The messages of a failed batch are dropped, the later batches
+are still delivered, and the error of the first failed batch is returned.
+*/
+func (s *Sink) Flush() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.mu.Lock()
+	msgs := s.pending
+	s.pending = nil
+	batch := s.batchSize()
+	s.mu.Unlock()
+	var first error
+	for len(msgs) > 0 {
+		n := batch
+		if n > len(msgs) {
+			n = len(msgs)
+		}
+		if err := s.p.WriteMessages(context.Background(), msgs[:n]...); err != nil && first == nil {
+			first = err
+		}
+		msgs = msgs[n:]
+	}
+	return first
+}
+
+/*Close delivers the pending messages and closes the producer, the entries handled afterwards are refused.*/
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		close(s.stop)
+		<-s.stopped
+	}
+	err := s.Flush()
+	if cerr := s.p.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package glogkafka
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/zydp/glog"
+)
+
+/*mockProducer records the batches it is given, failing the calls listed in fail.*/
+type mockProducer struct {
+	mu      sync.Mutex
+	batches [][]kafka.Message
+	calls   int
+	fail    map[int]bool // calls to fail, from 1
+	closed  bool
+}
+
+func (p *mockProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.calls++; p.fail[p.calls] {
+		return errors.New("broker down")
+	}
+	p.batches = append(p.batches, append([]kafka.Message(nil), msgs...))
+	return nil
+}
+
+func (p *mockProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *mockProducer) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, batch := range p.batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func TestSink(t *testing.T) {
+	p := &mockProducer{}
+	sink := NewSink(p)
+	sink.Formatter = &glog.TextFormatter{}
+	sink.KeyField = "user"
+	sink.BatchSize = 2
+	logger := glog.New(filepath.Join(t.TempDir(), "kafka.log"), "", 0)
+	defer logger.Close()
+	logger.SetHandler(sink)
+
+	logger.With("user", "yax").Info("login")
+	logger.Info("tick")
+	for deadline := time.Now().Add(5 * time.Second); p.count() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the full batch was not delivered")
+		}
+	}
+	logger.With("user", 7).Warn("retry")
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ key, value string }{{"yax", "[INFO]: login user=yax"}, {"", "[INFO]: tick"}, {"7", "[WARN]: retry user=7"}}
+	var got []kafka.Message
+	for _, batch := range p.batches {
+		got = append(got, batch...)
+	}
+	if len(got) != len(want) || len(p.batches) != 2 || !p.closed {
+		t.Fatalf("got %d messages in %d batches, closed %v", len(got), len(p.batches), p.closed)
+	}
+	for i, w := range want {
+		if string(got[i].Key) != w.key || string(got[i].Value) != w.value {
+			t.Errorf("message %d: key %q value %q, want %q %q", i, got[i].Key, got[i].Value, w.key, w.value)
+		}
+	}
+	if err := sink.Handle(&glog.Entry{}); err == nil {
+		t.Error("Handle after Close succeeded")
+	}
+}
+
+func TestSinkFailedBatch(t *testing.T) {
+	p := &mockProducer{fail: map[int]bool{1: true, 2: true}}
+	sink := NewSink(p)
+	sink.Formatter = &glog.TextFormatter{}
+	sink.BatchSize = 1
+	sink.FlushInterval = time.Hour
+	sink.OnError = func(error) {}
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := sink.Handle(&glog.Entry{Level: glog.NOLEVEL, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the batches of one message each may have gone out from the delivery goroutine already
+	if err := sink.Close(); err != nil && err.Error() != "broker down" {
+		t.Fatal(err)
+	}
+	if p.calls != 3 || len(p.batches) != 1 || string(p.batches[0][0].Value) != "three" {
+		t.Errorf("%d calls delivered %v, want only the last batch after the two failed ones", p.calls, p.batches)
+	}
+}
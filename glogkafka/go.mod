@@ -0,0 +1,13 @@
+module github.com/zydp/glog/glogkafka
+
+go 1.21
+
+require (
+	github.com/segmentio/kafka-go v0.4.47
+	github.com/zydp/glog v0.0.0-20261014153821-7b0f791e31c1
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
@@ -0,0 +1,117 @@
+/*
+Package glogr adapts a glog.Logger to the go-logr/logr interface, so that
+the code logging through logr can write its lines with glog:
+
+	log := logr.New(glogr.NewLogrSink(logger))
+	log.Info("connected", "addr", addr)
+
+It is a module of its own, so that glog does not pull in logr.
+*/
+package glogr
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/zydp/glog"
+)
+
+/*
+LogrSink is a logr.LogSink writing to a glog.Logger. Info lines of V(0) are
+written at INFO and those of V(1) and above at DEBUG, Error lines at ERROR
+with the error in an "error" field. The key/value pairs become the fields
+of the entries and the names given to WithName are joined with "/" in a
+"logger" field.
+*/
+type LogrSink struct {
+	l         *glog.Logger
+	name      string
+	fields    []glog.Field
+	callDepth int
+}
+
+/*NewLogrSink returns a logr.LogSink writing to l.*/
+func NewLogrSink(l *glog.Logger) *LogrSink {
+	return &LogrSink{l: l}
+}
+
+/*Init receives the call depth of the logr.Logger wrapping the sink.*/
+func (s *LogrSink) Init(info logr.RuntimeInfo) {
+	s.callDepth = info.CallDepth
+}
+
+/*level maps a logr verbosity to a glog level.*/
+func level(v int) int {
+	if v > 0 {
+		return glog.DEBUG
+	}
+	return glog.INFO
+}
+
+/*Enabled reports whether the lines of verbosity v pass the level of the logger.*/
+func (s *LogrSink) Enabled(v int) bool {
+	return level(v) >= s.l.Level()
+}
+
+/*Info writes msg at the level of verbosity v.*/
+func (s *LogrSink) Info(v int, msg string, keysAndValues ...interface{}) {
+	s.log(level(v), msg, nil, keysAndValues)
+}
+
+/*Error writes msg at ERROR with err in an "error" field.*/
+func (s *LogrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.log(glog.ERROR, msg, []glog.Field{{Key: "error", Value: err}}, keysAndValues)
+}
+
+func (s *LogrSink) log(level int, msg string, extra []glog.Field, keysAndValues []interface{}) {
+	e := glog.Entry{Time: time.Now(), Level: level, Message: msg}
+	// the caller of the logr.Logger method, past log and the sink method
+	if _, file, line, ok := runtime.Caller(s.callDepth + 2); ok {
+		e.File, e.Line = file, line
+	}
+	if s.name != "" {
+		e.Fields = append(e.Fields, glog.Field{Key: "logger", Value: s.name})
+	}
+	e.Fields = append(e.Fields, s.fields...)
+	e.Fields = append(e.Fields, extra...)
+	e.Fields = append(e.Fields, fields(keysAndValues)...)
+	_ = s.l.LogEntry(e)
+}
+
+/*WithValues returns a sink adding the given key/value pairs to each entry.*/
+func (s *LogrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.fields = append(s.fields[:len(s.fields):len(s.fields)], fields(keysAndValues)...)
+	return &c
+}
+
+/*WithName returns a sink appending name to the "logger" field.*/
+func (s *LogrSink) WithName(name string) logr.LogSink {
+	c := *s
+	c.name = strings.TrimPrefix(s.name+"/"+name, "/")
+	return &c
+}
+
+/*
+fields turns an alternating key/value list into fields, as glog does for
+With: non-string keys are rendered with fmt and a trailing key gets the
+value "!MISSING".
+*/
+func fields(keysAndValues []interface{}) []glog.Field {
+	fields := make([]glog.Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var value interface{} = "!MISSING"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields = append(fields, glog.Field{Key: key, Value: value})
+	}
+	return fields
+}
@@ -0,0 +1,36 @@
+package glogr
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/zydp/glog"
+)
+
+func TestLogrSink(t *testing.T) {
+	var buf bytes.Buffer
+	logger := glog.New(filepath.Join(t.TempDir(), "logr.log"), "", glog.Lshortfile)
+	defer logger.Close()
+	logger.SetOutput(&buf)
+	logger.SetLevel(glog.INFO)
+
+	log := logr.New(NewLogrSink(logger)).WithName("api").WithValues("req", 42)
+	log.Info("connected", "addr", "10.0.0.1")
+	log.V(1).Info("chatter")
+	log.WithName("db").Error(errors.New("gone"), "query failed", "table")
+	want := "glogr_test.go:22: [INFO]: connected logger=api req=42 addr=10.0.0.1\n" +
+		"glogr_test.go:24: [ERROR]: query failed logger=api/db req=42 error=gone table=!MISSING\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	logger.SetLevel(glog.DEBUG)
+	if log.V(1).Info("details"); !strings.Contains(buf.String(), "[DEBUG]: details") {
+		t.Errorf("V(1) got %q, want a DEBUG line", buf.String())
+	}
+}
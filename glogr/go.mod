@@ -0,0 +1,7 @@
+module github.com/zydp/glog/glogr
+
+go 1.21
+
+require github.com/zydp/glog v0.0.0-20261014153821-62864f8a6b0d
+
+require github.com/go-logr/logr v1.4.2
@@ -0,0 +1,3 @@
+module github.com/zydp/glog
+
+go 1.21
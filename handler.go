@@ -0,0 +1,104 @@
+package glog
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+/*
+A Handler receives the entries of a logger in place of its formatter and
+output, for sinks that need the structure of the entry rather than a
+formatted line, e.g. NewGELF. The logger calls Handle while holding its
+lock; a Handler shared by several loggers, child loggers from With included,
+has to be safe for concurrent use. The size based rotation, which accounts
+the bytes written to the output, does not apply to entries given to a Handler.
+*/
+type Handler interface {
+	Handle(e *Entry) error
+}
+
+/*SetHandler sets the Handler receiving the entries of the logger, nil restores the formatter and output.*/
+func (l *Logger) SetHandler(h Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handler = h
+}
+
+func SetHandler(h Handler) {
+	gStd.SetHandler(h)
+}
+
+/*
+A WriterHandler formats entries with its own Formatter and writes them to
+its own Writer, so that each sink of a MultiHandler can use a different
+format, e.g. colors for a terminal only. It is safe for concurrent use.
+*/
+type WriterHandler struct {
+	mu  sync.Mutex
+	w   io.Writer
+	f   Formatter
+	buf []byte
+}
+
+/*NewWriterHandler returns a Handler writing the entries formatted by f to w, a nil f means a TextFormatter with LstdFlags.*/
+func NewWriterHandler(w io.Writer, f Formatter) *WriterHandler {
+	if f == nil {
+		f = &TextFormatter{Flags: LstdFlags, Separator: " "}
+	}
+	return &WriterHandler{w: w, f: f}
+}
+
+/*Handle implements Handler.*/
+func (h *WriterHandler) Handle(e *Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf = h.f.Format(h.buf[:0], e)
+	_, err := h.w.Write(h.buf)
+	return err
+}
+
+type multiHandler []Handler
+
+/*MultiHandler returns a Handler handing every entry to each of the handlers in turn, it returns their joined errors.*/
+func MultiHandler(handlers ...Handler) Handler {
+	return multiHandler(append([]Handler(nil), handlers...))
+}
+
+func (m multiHandler) Handle(e *Entry) error {
+	var errs []error
+	for _, h := range m {
+		if err := h.Handle(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type levelRange struct {
+	min, max int
+	h        Handler
+}
+
+/*
+LevelRange returns a Handler handing h the entries from level min to max
+inclusive, e.g. LevelRange(ERROR, FATAL, h) for the errors only. The entries
+without a level, from the Print family, are NOLEVEL which is below DEBUG.
+Combined with MultiHandler and WriterHandlers over FileSinks, a single call
+fans out to files of different formats, levels and rotations:
+
+	logger.SetHandler(glog.MultiHandler(
+		glog.LevelRange(glog.ERROR, glog.FATAL, glog.NewWriterHandler(errorSink, &glog.JSONFormatter{})),
+		glog.NewWriterHandler(allSink, &glog.TextFormatter{Flags: glog.LstdFlags, Separator: " "}),
+	))
+*/
+func LevelRange(min, max int, h Handler) Handler {
+	return levelRange{min: min, max: max, h: h}
+}
+
+func (r levelRange) Handle(e *Entry) error {
+	if e.Level < r.min || e.Level > r.max {
+		return nil
+	}
+	return r.h.Handle(e)
+}
@@ -0,0 +1,68 @@
+package glog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingHandler struct{}
+
+func (failingHandler) Handle(*Entry) error { return errors.New("sink down") }
+
+func TestMultiHandlerColor(t *testing.T) {
+	var terminal, file bytes.Buffer
+	logger := newEx(nil, "", 0)
+	logger.SetHandler(MultiHandler(
+		NewWriterHandler(&terminal, &TextFormatter{Color: true}),
+		NewWriterHandler(&file, &TextFormatter{}),
+	))
+	logger.Err("boom")
+	logger.Info("fine")
+
+	if want := "\x1b[31m[ERROR]\x1b[0m: boom\n\x1b[32m[INFO]\x1b[0m: fine\n"; terminal.String() != want {
+		t.Errorf("terminal got %q, want %q", terminal.String(), want)
+	}
+	if strings.Contains(file.String(), "\x1b[") || file.String() != "[ERROR]: boom\n[INFO]: fine\n" {
+		t.Errorf("file got %q", file.String())
+	}
+
+	logger.SetHandler(MultiHandler(failingHandler{}, NewWriterHandler(&file, nil)))
+	if err := logger.Output(1, "still written"); err == nil || err.Error() != "sink down" {
+		t.Errorf("got error %v", err)
+	}
+	if !strings.HasSuffix(file.String(), " still written\n") {
+		t.Errorf("second sink skipped: %q", file.String())
+	}
+}
+
+func TestLevelRange(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile, textFile := filepath.Join(dir, "errors.json"), filepath.Join(dir, "all.log")
+	jsonSink, textSink := NewFileSink(jsonFile, 1, 5), NewFileSink(textFile, 10, 5)
+	defer jsonSink.Close()
+	defer textSink.Close()
+	logger := newEx(nil, "", 0)
+	logger.SetHandler(MultiHandler(
+		LevelRange(ERROR, FATAL, NewWriterHandler(jsonSink, &JSONFormatter{})),
+		NewWriterHandler(textSink, &TextFormatter{}),
+	))
+	logger.Info("started")
+	logger.Err("disk full")
+	logger.Println("plain")
+
+	if data, _ := os.ReadFile(textFile); string(data) != "[INFO]: started\n[ERROR]: disk full\nplain\n" {
+		t.Errorf("text file holds %q", data)
+	}
+	data, _ := os.ReadFile(jsonFile)
+	e, err := ParseJSONEntry(data)
+	if err != nil || e.Level != ERROR || e.Message != "disk full" || bytes.Count(data, []byte("\n")) != 1 {
+		t.Errorf("JSON file holds %q, parsed to %+v, %v", data, e, err)
+	}
+	if jsonSink.l.writtenSize != uint64(len(data)) {
+		t.Errorf("JSON sink accounted %d bytes, want %d", jsonSink.l.writtenSize, len(data))
+	}
+}
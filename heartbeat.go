@@ -0,0 +1,84 @@
+package glog
+
+import "time"
+
+/*heartbeat is a running StartHeartbeat.*/
+type heartbeat struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+/*
+StartHeartbeat logs msg every interval from a goroutine of the logger, at
+INFO unless SetHeartbeatLevel chose another level, so that a scraper watching
+the log can tell a quiet process from a dead one. It replaces the heartbeat
+already running, if any, and an interval of zero or less only stops that one.
+The heartbeat runs until StopHeartbeat, Reset or Close.
+*/
+func (l *Logger) StartHeartbeat(interval time.Duration, msg string) {
+	l.StopHeartbeat()
+	if interval <= 0 {
+		return
+	}
+	h := &heartbeat{stop: make(chan struct{}), done: make(chan struct{})}
+	l.mu.Lock()
+	if l.heartbeat != nil {
+		// lost a race with another StartHeartbeat, keep the heartbeat it started
+		l.mu.Unlock()
+		return
+	}
+	l.heartbeat = h
+	l.mu.Unlock()
+	go l.runHeartbeat(h, interval, msg)
+}
+
+func StartHeartbeat(interval time.Duration, msg string) {
+	gStd.StartHeartbeat(interval, msg)
+}
+
+/*StopHeartbeat stops the heartbeat and waits for its last line to be logged.*/
+func (l *Logger) StopHeartbeat() {
+	l.mu.Lock()
+	h := l.heartbeat
+	l.heartbeat = nil
+	l.mu.Unlock()
+	if h != nil {
+		close(h.stop)
+		<-h.done
+	}
+}
+
+func StopHeartbeat() {
+	gStd.StopHeartbeat()
+}
+
+/*SetHeartbeatLevel sets the level of the heartbeat lines, INFO by default, NOLEVEL writes them whatever the minimum level.*/
+func (l *Logger) SetHeartbeatLevel(level int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.beatLevel = level - INFO
+}
+
+func SetHeartbeatLevel(level int) {
+	gStd.SetHeartbeatLevel(level)
+}
+
+/*runHeartbeat logs msg every interval until h is stopped.*/
+func (l *Logger) runHeartbeat(h *heartbeat, interval time.Duration, msg string) {
+	defer close(h.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.stop:
+			return
+		}
+		l.mu.Lock()
+		level := INFO + l.beatLevel
+		l.mu.Unlock()
+		if l.enabled(level) {
+			l.output(2, level, nil, msg, nil)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package glog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	logger := newEx(&bytes.Buffer{}, "", 0)
+	logger.AddLineCallback(func(line []byte) {
+		mu.Lock()
+		lines = append(lines, string(line))
+		mu.Unlock()
+	})
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(lines)
+	}
+	logger.SetHeartbeatLevel(WARNING)
+	logger.StartHeartbeat(5*time.Millisecond, "alive")
+	for deadline := time.Now().Add(5 * time.Second); count() < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d heartbeats in 5s", count())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	logger.StopHeartbeat()
+	n := count()
+	time.Sleep(20 * time.Millisecond)
+	if count() != n {
+		t.Errorf("%d heartbeats after StopHeartbeat", count()-n)
+	}
+	mu.Lock()
+	if lines[0] != "[WARN]: alive\n" {
+		t.Errorf("got %q", lines[0])
+	}
+	mu.Unlock()
+
+	logger.StartHeartbeat(time.Millisecond, "alive")
+	logger.StartHeartbeat(time.Millisecond, "still alive") // replaces the first one
+	last := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lines[len(lines)-1]
+	}
+	for deadline := time.Now().Add(5 * time.Second); last() != "[WARN]: still alive\n"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q after the restart", last())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	logger.Close()
+	n = count()
+	time.Sleep(20 * time.Millisecond)
+	if count() != n {
+		t.Errorf("%d heartbeats after Close", count()-n)
+	}
+	if last() != "[WARN]: still alive\n" {
+		t.Errorf("got %q after Close", last())
+	}
+}
@@ -0,0 +1,73 @@
+package glog
+
+import "sync"
+
+var (
+	hooksMu     sync.RWMutex  // protects globalHooks
+	globalHooks []func(Entry) // run for the entries of every logger, replaced rather than appended to in place
+)
+
+/*
+AddHook registers fn to receive every entry the logger writes, once it has
+passed the level, the filter, the samplers and the rate limit, e.g. to count
+the errors or to mirror the entries to an audit trail. The entry carries the
+prefix, the fields of the logger and the whole message, and fn may keep it.
+Hooks run in order while the logger's lock is held, before the entry is
+written: they must return quickly and must not log to the same logger. A
+panicking hook is reported to the internal error writer. Child loggers from
+With run the hooks of l at the time of the call.
+*/
+func (l *Logger) AddHook(fn func(Entry)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks[:len(l.hooks):len(l.hooks)], fn)
+}
+
+func AddHook(fn func(Entry)) {
+	gStd.AddHook(fn)
+}
+
+/*
+AddGlobalHook registers fn to receive the entries of every logger of the
+process, after the hooks of the logger, e.g. for a central audit. It runs as
+the hooks of AddHook do, so it may be called from many goroutines at once.
+*/
+func AddGlobalHook(fn func(Entry)) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	globalHooks = append(globalHooks[:len(globalHooks):len(globalHooks)], fn)
+}
+
+/*ClearGlobalHooks unregisters the hooks of AddGlobalHook.*/
+func ClearGlobalHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	globalHooks = nil
+}
+
+func loadGlobalHooks() []func(Entry) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return globalHooks
+}
+
+/*runHooks hands e to the hooks of the logger then to the global ones, l.mu must be held.*/
+func (l *Logger) runHooks(e Entry, global []func(Entry)) {
+	e.Fields = append([]Field(nil), e.Fields...)
+	for _, fn := range l.hooks {
+		l.callHook(fn, e)
+	}
+	for _, fn := range global {
+		l.callHook(fn, e)
+	}
+}
+
+/*callHook hands e to the hook fn, reporting rather than propagating its panics.*/
+func (l *Logger) callHook(fn func(Entry), e Entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			l.internalError("hook panicked: %v", r)
+		}
+	}()
+	fn(e)
+}
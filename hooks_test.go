@@ -0,0 +1,69 @@
+package glog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestAddHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "[app] ", 0)
+	var got []Entry
+	logger.AddHook(func(e Entry) { got = append(got, e) })
+	logger.SetLevel(INFO)
+	logger.Debug("hidden")
+	logger.With("user", "yax").Info("login %d", 1)
+	logger.OutputBytes(1, []byte("raw"))
+	if len(got) != 2 {
+		t.Fatalf("hook got %d entries: %+v", len(got), got)
+	}
+	if e := got[0]; e.Level != INFO || e.Prefix != "[app] " || e.Message != "login 1" || len(e.Fields) != 1 || e.Fields[0].Key != "user" {
+		t.Errorf("first entry %+v", e)
+	}
+	if e := got[1]; e.Level != NOLEVEL || e.Message != "raw" {
+		t.Errorf("second entry %+v", e)
+	}
+
+	logger.AddHook(func(Entry) { panic("boom") })
+	var diag bytes.Buffer
+	logger.SetInternalErrorWriter(&diag)
+	buf.Reset()
+	logger.Info("still written")
+	if buf.String() != "[app] [INFO]: still written\n" || diag.String() != "glog: hook panicked: boom\n" {
+		t.Errorf("after a panicking hook wrote %q, reported %q", buf.String(), diag.String())
+	}
+}
+
+func TestAddGlobalHook(t *testing.T) {
+	defer ClearGlobalHooks()
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	AddGlobalHook(func(e Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[e.Prefix]++
+	})
+	var a, b bytes.Buffer
+	first, second := newEx(&a, "first ", 0), newEx(&b, "second ", 0)
+	var wg sync.WaitGroup
+	for _, logger := range []*Logger{first, second} {
+		wg.Add(1)
+		go func(logger *Logger) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logger.Info("entry %d", i)
+			}
+		}(logger)
+	}
+	wg.Wait()
+	if seen["first "] != 100 || seen["second "] != 100 {
+		t.Errorf("global hook saw %v", seen)
+	}
+
+	ClearGlobalHooks()
+	first.Info("unseen")
+	if seen["first "] != 100 {
+		t.Errorf("cleared global hook still ran")
+	}
+}
@@ -0,0 +1,98 @@
+package glog
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*journalSocket is the datagram socket of the journald native protocol.*/
+var journalSocket = "/run/systemd/journal/socket"
+
+/*
+A JournalHandler sends entries to systemd-journald over its native protocol
+with the fields SYSLOG_IDENTIFIER, PRIORITY, MESSAGE, CODE_FILE and CODE_LINE,
+plus the global fields and the fields attached with With as upper case keys.
+Entries are sent as single datagrams, so an entry must fit the socket buffer.
+It is safe for concurrent use.
+*/
+type JournalHandler struct {
+	Identifier string // SYSLOG_IDENTIFIER of the entries
+	conn       *net.UnixConn
+}
+
+/*NewJournald returns a Handler sending entries to the local journald as identifier, "" means the program name.*/
+func NewJournald(identifier string) (*JournalHandler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	return &JournalHandler{Identifier: identifier, conn: conn}, nil
+}
+
+/*Handle implements Handler.*/
+func (h *JournalHandler) Handle(e *Entry) error {
+	buf := appendJournalField(nil, "SYSLOG_IDENTIFIER", h.Identifier)
+	buf = appendJournalField(buf, "PRIORITY", strconv.Itoa(SyslogSeverity(e.Level)))
+	buf = appendJournalField(buf, "MESSAGE", e.Prefix+trimNewline(e.Message))
+	if e.File != "" {
+		buf = appendJournalField(buf, "CODE_FILE", e.File)
+		buf = appendJournalField(buf, "CODE_LINE", strconv.Itoa(e.Line))
+	}
+	for _, field := range GlobalFields() {
+		buf = appendJournalField(buf, journalKey(field.Key), string(appendValue(nil, field.Value)))
+	}
+	for _, field := range e.Fields {
+		buf = appendJournalField(buf, journalKey(field.Key), string(appendValue(nil, field.Value)))
+	}
+	_, err := h.conn.Write(buf)
+	return err
+}
+
+/*Close closes the socket.*/
+func (h *JournalHandler) Close() error {
+	return h.conn.Close()
+}
+
+/*
+appendJournalField writes KEY=value and a newline, or for a value holding
+newlines the key, a newline, the little endian 64 bits length and the value.
+*/
+func appendJournalField(buf []byte, key, value string) []byte {
+	buf = append(buf, key...)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf = append(buf, '=')
+	} else {
+		buf = append(buf, '\n')
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(len(value)))
+	}
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+/*
+journalKey turns a field key into a valid journal field name: upper case
+letters, digits and underscores, not starting with an underscore or a digit.
+*/
+func journalKey(key string) string {
+	b := make([]byte, 0, len(key)+2)
+	for _, c := range []byte(strings.ToUpper(key)) {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	for len(b) > 0 && b[0] == '_' {
+		b = b[1:]
+	}
+	if len(b) == 0 || b[0] >= '0' && b[0] <= '9' {
+		b = append([]byte("F_"), b...)
+	}
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*parseJournal decodes a native protocol datagram.*/
+func parseJournal(t *testing.T, p []byte) map[string]string {
+	fields := map[string]string{}
+	for len(p) > 0 {
+		i := bytes.IndexAny(p, "=\n")
+		if i < 0 {
+			t.Fatalf("truncated datagram %q", p)
+		}
+		key := string(p[:i])
+		if p[i] == '=' {
+			end := bytes.IndexByte(p, '\n')
+			fields[key] = string(p[i+1 : end])
+			p = p[end+1:]
+			continue
+		}
+		n := binary.LittleEndian.Uint64(p[i+1 : i+9])
+		fields[key] = string(p[i+9 : i+9+int(n)])
+		p = p[i+9+int(n)+1:]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip("no unixgram sockets:", err)
+	}
+	defer conn.Close()
+	journalSocket = path
+	defer func() { journalSocket = "/run/systemd/journal/socket" }()
+
+	h, err := NewJournald("glogtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := newEx(nil, "", Lshortfile)
+	logger.SetHandler(h)
+	read := func() map[string]string {
+		buf := make([]byte, 65536)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parseJournal(t, buf[:n])
+	}
+
+	logger.With("request-id", 42, "_user", "yax", "2fa", true).Warn("line one\nline two")
+	fields := read()
+	want := map[string]string{"SYSLOG_IDENTIFIER": "glogtest", "PRIORITY": "4", "MESSAGE": "line one\nline two",
+		"REQUEST_ID": "42", "USER": "yax", "F_2FA": "true", "CODE_FILE": fields["CODE_FILE"]}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %q, want %q", key, fields[key], value)
+		}
+	}
+	if filepath.Base(fields["CODE_FILE"]) != "journald_test.go" || fields["CODE_LINE"] == "" {
+		t.Errorf("bad code location %q:%q", fields["CODE_FILE"], fields["CODE_LINE"])
+	}
+
+	for level, priority := range map[int]string{DEBUG: "7", INFO: "6", ERROR: "3", FATAL: "2"} {
+		logger.Log(level).Msg("level")
+		if got := read()["PRIORITY"]; got != priority {
+			t.Errorf("level %d sent with PRIORITY %s, want %s", level, got, priority)
+		}
+	}
+}
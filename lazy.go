@@ -0,0 +1,27 @@
+package glog
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/*
+Lazy wraps an expensive logging argument so that it is only computed
+when the entry is actually written:
+
+	logger.Debug("state: %v", glog.Lazy(func() interface{} { return dump() }))
+
+An entry dropped by the level filter never formats its arguments, so the
+function is not called. Lazy works as a format argument and as a field value.
+*/
+type Lazy func() interface{}
+
+/*Format implements fmt.Formatter by formatting the computed value with the same verb and flags.*/
+func (f Lazy) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, fmt.FormatString(s, verb), f())
+}
+
+/*MarshalJSON implements json.Marshaler by marshaling the computed value.*/
+func (f Lazy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f())
+}
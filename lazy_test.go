@@ -0,0 +1,34 @@
+package glog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	var buf bytes.Buffer
+	calls := 0
+	dump := Lazy(func() interface{} {
+		calls++
+		return 42
+	})
+	logger := newEx(&buf, "", 0)
+	logger.SetLevel(INFO)
+	logger.Debug("state: %v", dump)
+	logger.Log(DEBUG).Any("state", dump).Msg("skipped")
+	if calls != 0 || buf.Len() != 0 {
+		t.Fatalf("suppressed entries called the function %d times and wrote %q", calls, buf.String())
+	}
+
+	logger.Info("state: %05d", dump)
+	logger.Log(INFO).Any("state", dump).Msg("kept")
+	logger.SetFormatter(&JSONFormatter{TimeFormat: "-"})
+	logger.Log(WARNING).Any("state", dump).Msg("json")
+	want := "[INFO]: state: 00042\n[INFO]: kept state=42\n" + `{"time":"-","level":"WARN","msg":"json","state":42}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+	if calls != 3 {
+		t.Errorf("function called %d times, want 3", calls)
+	}
+}
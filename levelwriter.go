@@ -0,0 +1,169 @@
+package glog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+/*LEVEL_WRITER_MAX_LINE is the default longest line of a LevelWriter, see SetMaxLineSize.*/
+const LEVEL_WRITER_MAX_LINE = 1024 * 1024
+
+/*
+A LevelWriter logs the lines written to it, e.g. the output of a subprocess,
+each as an entry of its logger at the level the line starts with:
+
+	w := logger.NewLevelWriter(glog.INFO)
+	cmd.Stdout, cmd.Stderr = w, w
+	err := cmd.Run()
+	w.Close()
+
+A line starting with a level name, alone or as in "[WARN]", "ERROR:" or
+"level=debug", case insensitive, is logged at that level, the others at the
+level of NewLevelWriter; the line is logged as is, level name included. The
+lines may end with "\n" or "\r\n". A line longer than the maximum line size
+is logged in pieces of that size, so that a writer never buffers more than
+it; Write returns once the lines of p are taken, holding a fast writer back
+rather than buffering its output. Close logs the final line when it has no
+newline. A LevelWriter is safe for concurrent use, the lines of
+concurrent writes may then interleave.
+*/
+type LevelWriter struct {
+	l       *Logger
+	level   int
+	mu      sync.Mutex
+	maxLine int
+	pw      *io.PipeWriter // feeds the scanner, nil until the first write
+	done    chan struct{}
+	closed  bool
+}
+
+/*NewLevelWriter returns a LevelWriter logging through l, at level the lines without a level of their own.*/
+func (l *Logger) NewLevelWriter(level int) *LevelWriter {
+	return &LevelWriter{l: l, level: level, maxLine: LEVEL_WRITER_MAX_LINE}
+}
+
+func NewLevelWriter(level int) *LevelWriter {
+	return gStd.NewLevelWriter(level)
+}
+
+/*SetMaxLineSize sets the longest line logged whole, LEVEL_WRITER_MAX_LINE when n is 0 or less. It must be called before the first write.*/
+func (w *LevelWriter) SetMaxLineSize(n int) {
+	if n <= 0 {
+		n = LEVEL_WRITER_MAX_LINE
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxLine = n
+}
+
+/*Write logs the complete lines of p, keeping the final partial one for the next write or Close.*/
+func (w *LevelWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if w.pw == nil {
+		pr, pw := io.Pipe()
+		w.pw, w.done = pw, make(chan struct{})
+		go w.run(pr, w.maxLine)
+	}
+	pw := w.pw
+	w.mu.Unlock()
+	return pw.Write(p)
+}
+
+/*run logs the lines scanned from r until it is closed.*/
+func (w *LevelWriter) run(r *io.PipeReader, maxLine int) {
+	defer close(w.done)
+	scanner := bufio.NewScanner(r)
+	initial := 4096
+	if maxLine < initial {
+		initial = maxLine
+	}
+	scanner.Buffer(make([]byte, initial), maxLine+2) // room for the "\r\n" of a line of maxLine bytes
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		return scanLine(data, atEOF, maxLine)
+	})
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		level := detectLevel(line, w.level)
+		if w.l.enabled(level) {
+			w.l.output(noCaller, level, nil, string(line), nil)
+		}
+	}
+	r.CloseWithError(scanner.Err()) // unblocks the writers after an error
+}
+
+/*
+scanLine is a bufio.SplitFunc returning the lines without their "\n" or
+"\r\n", or the first maxLine bytes of a longer line, cut on a character
+boundary when possible.
+*/
+func scanLine(data []byte, atEOF bool, maxLine int) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		if line := bytes.TrimSuffix(data[:i], []byte{'\r'}); len(line) <= maxLine {
+			return i + 1, line, nil
+		}
+	}
+	if len(data) > maxLine && (atEOF || len(data) >= maxLine+2) {
+		// the line does not end within reach, so cutting it leaves no lone newline behind
+		n := maxLine
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		if n == 0 {
+			n = maxLine
+		}
+		return n, data[:n], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), bytes.TrimSuffix(data, []byte{'\r'}), nil
+	}
+	return 0, nil, nil
+}
+
+/*detectLevel returns the level the line starts with, or def.*/
+func detectLevel(line []byte, def int) int {
+	i := bytes.IndexAny(line, " \t")
+	if i < 0 {
+		i = len(line)
+	}
+	word := strings.ToLower(strings.Trim(string(line[:i]), "[]():|"))
+	word = strings.TrimPrefix(word, "level=")
+	switch word {
+	case "debug", "trace":
+		return DEBUG
+	case "info", "notice":
+		return INFO
+	case "warn", "warning":
+		return WARNING
+	case "error", "err":
+		return ERROR
+	case "fatal", "panic", "crit", "critical":
+		return FATAL
+	}
+	return def
+}
+
+/*Close logs the final line and waits for the lines written to be logged. Writes after Close fail.*/
+func (w *LevelWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	pw, done := w.pw, w.done
+	w.mu.Unlock()
+	if pw == nil {
+		return nil
+	}
+	pw.Close()
+	<-done
+	return nil
+}
@@ -0,0 +1,39 @@
+package glog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLevelWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	w := logger.NewLevelWriter(INFO)
+	long := strings.Repeat("x", 100*1024) // longer than the default buffer of a bufio.Scanner
+	for _, chunk := range []string{"starting\r\n[WARN] disk ", "almost full\nERROR: failed\n" + long, "\nlevel=debug details\ntail"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "[INFO]: starting\n[WARN]: [WARN] disk almost full\n[ERROR]: ERROR: failed\n[INFO]: " + long +
+		"\n[DEBUG]: level=debug details\n[INFO]: tail\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %.200q", got)
+	}
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+
+	buf.Reset()
+	w = logger.NewLevelWriter(WARNING)
+	w.SetMaxLineSize(4)
+	w.Write([]byte("abcdefghij\r\nabcd\r\nabé\n"))
+	w.Close()
+	if got := buf.String(); got != "[WARN]: abcd\n[WARN]: efgh\n[WARN]: ij\n[WARN]: abcd\n[WARN]: abé\n" {
+		t.Errorf("with a max line size of 4 got %q", got)
+	}
+}
@@ -0,0 +1,80 @@
+package glog
+
+import (
+	"io"
+	"sync"
+)
+
+/*
+A LineBuffer is an output keeping the last lines written to it in memory,
+one line per write as a Logger writes them, e.g. to serve the recent log of
+a process over HTTP:
+
+	recent := glog.NewLineBuffer(1000)
+	logger := recent.NewLogger("", glog.LstdFlags)
+	http.HandleFunc("/log", func(w http.ResponseWriter, r *http.Request) {
+		recent.WriteTo(w)
+	})
+
+Once n lines are held, each new one replaces the oldest. A LineBuffer is safe
+for concurrent use.
+*/
+type LineBuffer struct {
+	mu    sync.Mutex
+	lines [][]byte // ring of the lines, the oldest at next once full
+	next  int
+	full  bool
+}
+
+/*NewLineBuffer returns a LineBuffer holding up to n lines, at least one.*/
+func NewLineBuffer(n int) *LineBuffer {
+	if n < 1 {
+		n = 1
+	}
+	return &LineBuffer{lines: make([][]byte, n)}
+}
+
+/*NewLogger creates a Logger writing to the buffer, with the rotation disabled.*/
+func (b *LineBuffer) NewLogger(prefix string, flag int) *Logger {
+	l := newEx(b, prefix, flag)
+	l.splitFileSize = 0
+	return l
+}
+
+/*Write stores a copy of p as the newest line.*/
+func (b *LineBuffer) Write(p []byte) (int, error) {
+	line := append([]byte(nil), p...)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[b.next] = line
+	if b.next++; b.next == len(b.lines) {
+		b.next, b.full = 0, true
+	}
+	return len(p), nil
+}
+
+/*
+WriteTo implements io.WriterTo, writing the lines held, oldest first, to w
+one write per line. The lines are written as held when WriteTo is called,
+without copying them and without holding the buffer, so that a slow w does
+not block the loggers writing to it.
+*/
+func (b *LineBuffer) WriteTo(w io.Writer) (int64, error) {
+	b.mu.Lock()
+	// only the slots are reused, a line is never modified once stored
+	var lines [][]byte
+	if b.full {
+		lines = append(lines, b.lines[b.next:]...)
+	}
+	lines = append(lines, b.lines[:b.next]...)
+	b.mu.Unlock()
+	var total int64
+	for _, line := range lines {
+		n, err := w.Write(line)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
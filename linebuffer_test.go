@@ -0,0 +1,30 @@
+package glog
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestLineBufferWriteTo(t *testing.T) {
+	b := NewLineBuffer(3)
+	logger := b.NewLogger("", 0)
+	var buf bytes.Buffer
+	if n, err := b.WriteTo(&buf); n != 0 || err != nil || buf.Len() != 0 {
+		t.Errorf("empty buffer: wrote %d bytes, %v", n, err)
+	}
+	logger.Info("one")
+	logger.Info("two")
+	if n, err := b.WriteTo(&buf); n != int64(buf.Len()) || err != nil || buf.String() != "[INFO]: one\n[INFO]: two\n" {
+		t.Errorf("got %d bytes, %v: %q", n, err, buf.String())
+	}
+	for _, msg := range []string{"three", "four", "five"} {
+		logger.Info("%s", msg)
+	}
+	buf.Reset()
+	var w io.WriterTo = b
+	want := "[INFO]: three\n[INFO]: four\n[INFO]: five\n"
+	if n, err := w.WriteTo(&buf); n != int64(len(want)) || err != nil || buf.String() != want {
+		t.Errorf("got %d bytes, %v: %q, want %d bytes: %q", n, err, buf.String(), len(want), want)
+	}
+}
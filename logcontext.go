@@ -0,0 +1,67 @@
+package glog
+
+import "fmt"
+
+/*
+A LogContext carries fields for the entries of a unit of work, e.g. a request,
+and logs them through its logger: it is lighter than a child logger from With,
+being only the logger and the fields, so it can be made per request and passed
+down to where the logging happens:
+
+	ctx := logger.NewContext("request", id)
+	ctx.Info("loading %s", path) // [INFO]: loading /a request=42
+
+The entries are the logger's own, with its settings and its fields followed by
+those of the context. A LogContext is safe for concurrent use.
+*/
+type LogContext struct {
+	l      *Logger
+	fields []Field
+}
+
+/*NewContext returns a LogContext logging through l with the fields of the alternating keys and values of keyvals, as With takes them.*/
+func (l *Logger) NewContext(keyvals ...interface{}) *LogContext {
+	return &LogContext{l: l, fields: fieldsFromKeyvals(keyvals)}
+}
+
+func NewContext(keyvals ...interface{}) *LogContext {
+	return gStd.NewContext(keyvals...)
+}
+
+/*With returns a LogContext carrying the fields of c followed by those of keyvals.*/
+func (c *LogContext) With(keyvals ...interface{}) *LogContext {
+	fields := append(c.fields[:len(c.fields):len(c.fields)], fieldsFromKeyvals(keyvals)...)
+	return &LogContext{l: c.l, fields: fields}
+}
+
+/*entryFields returns the fields of c for an entry, which may append to them but not overwrite them.*/
+func (c *LogContext) entryFields() []Field {
+	return c.fields[:len(c.fields):len(c.fields)]
+}
+
+func (c *LogContext) Debug(format string, v ...interface{}) {
+	if c.l.enabled(DEBUG) {
+		c.l.output(2, DEBUG, c.entryFields(), fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *LogContext) Info(format string, v ...interface{}) {
+	if c.l.enabled(INFO) {
+		c.l.output(2, INFO, c.entryFields(), fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *LogContext) Warn(format string, v ...interface{}) {
+	if c.l.enabled(WARNING) {
+		c.l.output(2, WARNING, c.entryFields(), fmt.Sprintf(format, v...), nil)
+	}
+}
+
+/*Err logs at ERROR, subject to the error cooldown of the logger as Logger.Err is.*/
+func (c *LogContext) Err(format string, v ...interface{}) {
+	if c.l.enabled(ERROR) {
+		if fields, ok := c.l.coolDown(format, c.entryFields()); ok {
+			c.l.output(2, ERROR, fields, fmt.Sprintf(format, v...), nil)
+		}
+	}
+}
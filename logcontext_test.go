@@ -0,0 +1,33 @@
+package glog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLogContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger = logger.With("app", "api")
+	first := logger.NewContext("request", 1)
+	second := logger.NewContext("request", 2, "user", "yax")
+	first.Info("loading %s", "/a")
+	second.Warn("slow")
+	first.Err("failed")
+	logger.Info("plain")
+	base := logger.NewContext("request", 3)
+	a, b := base.With("step", "a"), base.With("step", "b")
+	a.Debug("x")
+	b.Debug("y")
+	base.Info("z")
+	want := "[INFO]: loading /a app=api request=1\n" +
+		"[WARN]: slow app=api request=2 user=yax\n" +
+		"[ERROR]: failed app=api request=1\n" +
+		"[INFO]: plain app=api\n" +
+		"[DEBUG]: x app=api request=3 step=a\n" +
+		"[DEBUG]: y app=api request=3 step=b\n" +
+		"[INFO]: z app=api request=3\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,120 @@
+package glog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+LogfmtFormatter renders each entry as one logfmt line, key=value pairs with
+the keys "time", "seq", "id", "level", "prefix", "file", "line", "pkg", "func"
+and "msg" followed by the global fields and the entry fields:
+
+	time=2024-05-01T10:00:00Z level=info msg="request done" path=/a took=3ms
+
+The level is in lower case and, as in JSONFormatter, the keys without a value
+for the entry are left out. A value is quoted when it is empty or holds a
+space, an equals sign, a quote or a character that is not printable, with
+the quotes, the backslashes and the control characters escaped as in Go.
+The characters of the keys that a logfmt key cannot hold are replaced with
+underscores.
+*/
+type LogfmtFormatter struct {
+	TimeFormat string // layout of the "time" value, "" means time.RFC3339Nano
+	UTC        bool   // render the time in UTC rather than in the local time zone
+}
+
+/*Format implements Formatter.*/
+func (f *LogfmtFormatter) Format(buf []byte, e *Entry) []byte {
+	t := e.Time
+	if f.UTC {
+		t = t.UTC()
+	}
+	layout := f.TimeFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	buf = append(buf, "time="...)
+	buf = appendLogfmtValue(buf, t.Format(layout))
+	if e.Seq != 0 {
+		buf = append(buf, " seq="...)
+		buf = strconv.AppendUint(buf, e.Seq, 10)
+	}
+	if e.ID != "" {
+		buf = append(buf, " id="...)
+		buf = appendLogfmtValue(buf, e.ID)
+	}
+	if e.Level != NOLEVEL {
+		buf = append(buf, " level="...)
+		buf = appendLogfmtValue(buf, strings.ToLower(levelName(e.Level)))
+	}
+	if e.Prefix != "" {
+		buf = append(buf, " prefix="...)
+		buf = appendLogfmtValue(buf, e.Prefix)
+	}
+	if e.File != "" {
+		buf = append(buf, " file="...)
+		buf = appendLogfmtValue(buf, e.File)
+		buf = append(buf, " line="...)
+		buf = strconv.AppendInt(buf, int64(e.Line), 10)
+	}
+	if e.Package != "" {
+		buf = append(buf, " pkg="...)
+		buf = appendLogfmtValue(buf, e.Package)
+	}
+	if e.Func != "" {
+		buf = append(buf, " func="...)
+		buf = appendLogfmtValue(buf, e.Func)
+	}
+	buf = append(buf, " msg="...)
+	buf = appendLogfmtValue(buf, trimNewline(e.Message))
+	for _, field := range GlobalFields() {
+		buf = appendLogfmtField(buf, field)
+	}
+	for _, field := range e.Fields {
+		buf = appendLogfmtField(buf, field)
+	}
+	return append(buf, '\n')
+}
+
+func appendLogfmtField(buf []byte, field Field) []byte {
+	buf = append(buf, ' ')
+	if field.Key == "" {
+		buf = append(buf, '_')
+	}
+	for _, r := range field.Key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !strconv.IsPrint(r) {
+			r = '_'
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	buf = append(buf, '=')
+	start := len(buf)
+	buf = appendValue(buf, field.Value)
+	if value := string(buf[start:]); logfmtNeedsQuotes(value) {
+		return strconv.AppendQuote(buf[:start], value)
+	}
+	return buf
+}
+
+/*appendLogfmtValue writes s, quoted if logfmt needs it to be.*/
+func appendLogfmtValue(buf []byte, s string) []byte {
+	if logfmtNeedsQuotes(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func logfmtNeedsQuotes(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
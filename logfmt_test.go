@@ -0,0 +1,41 @@
+package glog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	f := &LogfmtFormatter{UTC: true}
+	e := &Entry{
+		Time:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Level:   WARNING,
+		Prefix:  "[api] ",
+		File:    "main.go",
+		Line:    12,
+		Message: "said \"hi\" to a=b\n",
+		Fields: []Field{
+			{Key: "path", Value: "/a"},
+			{Key: "quote", Value: `she said "no way"`},
+			{Key: "empty", Value: ""},
+			{Key: "multi line", Value: "a\nb"},
+			{Key: "took", Value: 3 * time.Millisecond},
+			{Key: "err", Value: errors.New(`open "x": denied`)},
+		},
+	}
+	want := `time=2024-05-01T10:00:00Z level=warn prefix="[api] " file=main.go line=12 msg="said \"hi\" to a=b"` +
+		` path=/a quote="she said \"no way\"" empty="" multi_line="a\nb" took=3ms err="open \"x\": denied"` + "\n"
+	if got := string(f.Format(nil, e)); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetFormatter(&LogfmtFormatter{TimeFormat: "15:04"})
+	logger.With("user", "yax").Println("plain")
+	if got := buf.String(); len(got) < 11 || got[10:] != " msg=plain user=yax\n" {
+		t.Errorf("got %q", got)
+	}
+}
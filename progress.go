@@ -0,0 +1,45 @@
+package glog
+
+import "time"
+
+/*progressMark is what Progress remembers of its previous call for a key.*/
+type progressMark struct {
+	t    time.Time
+	done int64
+}
+
+/*
+Progress logs at INFO how far the task named key has got, as the fields done
+and total, and its rate in units per second since the previous call for the
+same key as the field rate, which the first call for a key leaves out:
+
+	[INFO]: upload done=600 total=1000 rate=150
+
+A done equal to total forgets the key.
+*/
+func (l *Logger) Progress(key string, done, total int64) {
+	if !l.enabled(INFO) {
+		return
+	}
+	t := now()
+	fields := []Field{{Key: "done", Value: done}, {Key: "total", Value: total}}
+	l.progressMu.Lock()
+	if last, ok := l.progress[key]; ok {
+		if elapsed := t.Sub(last.t).Seconds(); elapsed > 0 {
+			fields = append(fields, Field{Key: "rate", Value: float64(done-last.done) / elapsed})
+		}
+	}
+	if done >= total {
+		delete(l.progress, key)
+	} else {
+		if l.progress == nil {
+			l.progress = make(map[string]progressMark)
+		}
+		l.progress[key] = progressMark{t: t, done: done}
+	}
+	l.progressMu.Unlock()
+	l.output(2, INFO, fields, key, nil)
+}
+func Progress(key string, done, total int64) {
+	gStd.Progress(key, done, total)
+}
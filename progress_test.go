@@ -0,0 +1,32 @@
+package glog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	clock := time.Date(2009, 1, 23, 1, 23, 23, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.Progress("upload", 300, 1000)
+	clock = clock.Add(2 * time.Second)
+	logger.Progress("upload", 600, 1000)
+	logger.Progress("download", 1, 2)
+	clock = clock.Add(4 * time.Second)
+	logger.Progress("upload", 1000, 1000)
+	want := "[INFO]: upload done=300 total=1000\n" +
+		"[INFO]: upload done=600 total=1000 rate=150\n" +
+		"[INFO]: download done=1 total=2\n" +
+		"[INFO]: upload done=1000 total=1000 rate=100\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+	if len(logger.progress) != 1 {
+		t.Errorf("%d keys tracked, want the unfinished download only", len(logger.progress))
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package glog
+
+import "time"
+
+/*The renames of the rotation do not fail on an open file here, so they are neither retried nor replaced by default.*/
+const (
+	defaultRenameRetries = 0
+	defaultRenameDelay   = time.Duration(0)
+	defaultCopyTruncate  = false
+)
@@ -0,0 +1,16 @@
+//go:build windows
+
+package glog
+
+import "time"
+
+/*
+A rename fails on Windows while another process holds the file open, e.g. a
+tailer reading it, so the rotation retries it for a while then copies the
+file over and truncates it.
+*/
+const (
+	defaultRenameRetries = 3
+	defaultRenameDelay   = 10 * time.Millisecond
+	defaultCopyTruncate  = true
+)
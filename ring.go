@@ -0,0 +1,154 @@
+package glog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+ring is a bounded lock-free queue of async items for many producers and a
+single consumer, the writer goroutine, after Dmitry Vyukov's bounded queue:
+each slot carries a sequence number telling whether it is free for the
+position a producer claimed or holds the item of the position the consumer
+is at, so that producers only contend on a compare-and-swap of the tail
+instead of the lock of a channel.
+
+The consumer sleeps on wake when the ring is empty and the producers waiting
+for room sleep on room, each side only signalling when the other is asleep.
+*/
+type ring struct {
+	slots []ringSlot
+	n     uint64
+	_     [56]byte // keeps tail on a cache line of its own
+	tail  uint64   // next position claimed by a producer, accessed atomically
+	_     [56]byte
+	head  uint64 // next position read by the consumer, written by it only, accessed atomically
+
+	sleeping uint32        // the consumer waits on wake, accessed atomically
+	waiting  int32         // producers waiting on room, accessed atomically
+	closed   uint32        // set once no producer will push again, accessed atomically
+	wake     chan struct{} // wakes the consumer
+	room     chan struct{} // wakes a producer waiting for room
+}
+
+type ringSlot struct {
+	seq  uint64 // twice the position the slot is free for, plus one once it holds its item, accessed atomically
+	item asyncItem
+}
+
+func newRing(size int) *ring {
+	r := &ring{slots: make([]ringSlot, size), n: uint64(size), wake: make(chan struct{}, 1), room: make(chan struct{}, 1)}
+	for i := range r.slots {
+		r.slots[i].seq = 2 * uint64(i)
+	}
+	return r
+}
+
+/*len returns the number of items in the ring, those being written by producers included.*/
+func (r *ring) len() int {
+	head := atomic.LoadUint64(&r.head) // first, so that the tail read next cannot be behind it
+	return int(atomic.LoadUint64(&r.tail) - head)
+}
+
+/*tryPush queues item unless the ring is full.*/
+func (r *ring) tryPush(item asyncItem) bool {
+	for {
+		pos := atomic.LoadUint64(&r.tail)
+		s := &r.slots[pos%r.n]
+		seq := atomic.LoadUint64(&s.seq)
+		if seq == 2*pos {
+			if atomic.CompareAndSwapUint64(&r.tail, pos, pos+1) {
+				s.item = item
+				atomic.StoreUint64(&s.seq, 2*pos+1)
+				if atomic.LoadUint32(&r.sleeping) != 0 {
+					wakeUp(r.wake)
+				}
+				return true
+			}
+		} else if seq < 2*pos {
+			return false // the consumer has not read the item of the previous round yet
+		}
+		// another producer claimed pos first, retry with the new tail
+	}
+}
+
+/*
+push queues item, waiting for room until timeout fires or cancel is closed,
+either of which may be nil to wait as long as needed. It reports whether
+item was queued.
+*/
+func (r *ring) push(item asyncItem, timeout <-chan time.Time, cancel <-chan struct{}) bool {
+	if r.tryPush(item) {
+		return true
+	}
+	atomic.AddInt32(&r.waiting, 1)
+	defer atomic.AddInt32(&r.waiting, -1)
+	for !r.tryPush(item) {
+		select {
+		case <-r.room:
+		case <-timeout:
+			return false
+		case <-cancel:
+			return false
+		}
+	}
+	// pass the wakeup on in case the room left suits another waiter
+	if r.len() < int(r.n) && atomic.LoadInt32(&r.waiting) > 1 {
+		wakeUp(r.room)
+	}
+	return true
+}
+
+/*tryPop takes the next item unless the ring is empty, the consumer only.*/
+func (r *ring) tryPop() (asyncItem, bool) {
+	head := atomic.LoadUint64(&r.head)
+	s := &r.slots[head%r.n]
+	if atomic.LoadUint64(&s.seq) != 2*head+1 {
+		return asyncItem{}, false
+	}
+	item := s.item
+	s.item = asyncItem{}
+	atomic.StoreUint64(&s.seq, 2*(head+r.n))
+	atomic.StoreUint64(&r.head, head+1)
+	if atomic.LoadInt32(&r.waiting) != 0 {
+		wakeUp(r.room)
+	}
+	return item, true
+}
+
+/*pop takes the next item, waiting for one, until the ring is closed and drained, the consumer only.*/
+func (r *ring) pop() (asyncItem, bool) {
+	for {
+		closed := atomic.LoadUint32(&r.closed) != 0
+		if item, ok := r.tryPop(); ok {
+			return item, true
+		}
+		if closed {
+			return asyncItem{}, false
+		}
+		atomic.StoreUint32(&r.sleeping, 1)
+		// a producer which missed the flag has published its item by now
+		if item, ok := r.tryPop(); ok {
+			atomic.StoreUint32(&r.sleeping, 0)
+			return item, true
+		}
+		if atomic.LoadUint32(&r.closed) == 0 {
+			<-r.wake
+		}
+		atomic.StoreUint32(&r.sleeping, 0)
+	}
+}
+
+/*close lets the consumer return once the ring is drained, the producers must be done pushing.*/
+func (r *ring) close() {
+	atomic.StoreUint32(&r.closed, 1)
+	wakeUp(r.wake)
+}
+
+/*wakeUp posts a wakeup on c unless one is pending already.*/
+func wakeUp(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,126 @@
+package glog
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*TestRing checks every item of concurrent producers is popped once and in the order of its producer, run it with "go test -race".*/
+func TestRing(t *testing.T) {
+	const goroutines, count = 10, 5000
+	r := newRing(7) // small and not a power of two, so that the producers often wait for room
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				r.push(asyncItem{e: &Entry{Level: g, Line: i}}, nil, nil)
+			}
+		}(g)
+	}
+	go func() {
+		wg.Wait()
+		r.close()
+	}()
+	var next [goroutines]int
+	for {
+		item, ok := r.pop()
+		if !ok {
+			break
+		}
+		if g := item.e.Level; item.e.Line != next[g] {
+			t.Fatalf("producer %d: got item %d, want %d", g, item.e.Line, next[g])
+		}
+		next[item.e.Level]++
+	}
+	for g, n := range next {
+		if n != count {
+			t.Errorf("producer %d: popped %d items, want %d", g, n, count)
+		}
+	}
+}
+
+func TestRingPushTimeout(t *testing.T) {
+	r := newRing(1)
+	if !r.tryPush(asyncItem{}) || r.tryPush(asyncItem{}) {
+		t.Fatal("a ring of one holds one item")
+	}
+	if r.push(asyncItem{}, time.After(10*time.Millisecond), nil) {
+		t.Error("push into a full ring succeeded")
+	}
+	cancel := make(chan struct{})
+	close(cancel)
+	if r.push(asyncItem{}, nil, cancel) {
+		t.Error("cancelled push into a full ring succeeded")
+	}
+	if _, ok := r.tryPop(); !ok || r.len() != 0 {
+		t.Errorf("pop failed, %d items left", r.len())
+	}
+}
+
+/*benchmarkQueue pushes b.N items from 10 goroutines, as TestOutputRace does, into a queue of 1024 drained by one consumer.*/
+func benchmarkQueue(b *testing.B, push func(asyncItem), consume func()) {
+	const goroutines = 10
+	done := make(chan struct{})
+	go func() {
+		consume()
+		close(done)
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		n := b.N / goroutines
+		if g == 0 {
+			n += b.N % goroutines
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			e := &Entry{}
+			for i := 0; i < n; i++ {
+				push(asyncItem{e: e})
+			}
+		}(n)
+	}
+	wg.Wait()
+	push(asyncItem{}) // stops consume
+	<-done
+}
+
+func BenchmarkRing(b *testing.B) {
+	r := newRing(1024)
+	benchmarkQueue(b, func(item asyncItem) { r.push(item, nil, nil) }, func() {
+		for {
+			if item, _ := r.pop(); item.e == nil {
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkChannel(b *testing.B) {
+	ch := make(chan asyncItem, 1024)
+	benchmarkQueue(b, func(item asyncItem) { ch <- item }, func() {
+		for item := range ch {
+			if item.e == nil {
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkAsync(b *testing.B) {
+	logger := newEx(io.Discard, "[Info] ", LstdFlags)
+	logger.SetAsync(&AsyncConfig{})
+	defer logger.SetAsync(nil)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("abcdefghijklmnopqrstuvwxyz0123456789")
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package glog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*levelSampling keeps one entry in every[level] of the levels below WARNING.*/
+type levelSampling struct {
+	every [WARNING]uint64
+	count [WARNING]uint64 // entries seen per level, accessed atomically
+}
+
+/*
+SetLevelSampling keeps only one entry in n of a level, per the map of the
+levels to their n, e.g. {DEBUG: 100, INFO: 10} to write the first of every
+hundred debug lines and of every ten info lines. The entries are dropped
+before their message is formatted. WARNING and the levels above are always
+kept whatever the map, as are the levels missing from it or with an n of 1
+or less. A nil map keeps everything again. Child loggers from With share
+the sampling of l at the time of the call.
+*/
+func (l *Logger) SetLevelSampling(n map[int]int) {
+	var s *levelSampling
+	for level, every := range n {
+		if level >= DEBUG && level < WARNING && every > 1 {
+			if s == nil {
+				s = &levelSampling{}
+			}
+			s.every[level] = uint64(every)
+		}
+	}
+	l.sampling.Store(s)
+}
+
+func SetLevelSampling(n map[int]int) {
+	gStd.SetLevelSampling(n)
+}
+
+/*keep reports whether the next entry of level is kept.*/
+func (s *levelSampling) keep(level int) bool {
+	if level < DEBUG || level >= WARNING || s.every[level] == 0 {
+		return true
+	}
+	return (atomic.AddUint64(&s.count[level], 1)-1)%s.every[level] == 0
+}
+
+/*burstKeys bounds the number of messages a burst sampler counts at once.*/
+const burstKeys = 1024
+
+/*burstSampler counts the entries per message, see SetBurstSampler.*/
+type burstSampler struct {
+	mu         sync.Mutex // protects counts, as the children of With share the sampler
+	burst      uint64
+	thereafter uint64
+	counts     map[string]uint64
+}
+
+/*
+SetBurstSampler writes the first burst entries of each message in full, then
+only one in thereafter of the repeats, so that the start of a problem shows
+while its flood does not drown the rest; a thereafter of 0 drops all the
+repeats. The entries are told apart by their formatted message, and up to
+1024 messages are counted at once: when more come, the counts start over.
+A burst of 0 or less turns the sampler off. Child loggers from With share
+the sampler of l at the time of the call, so that a message repeated across
+them is counted once.
+*/
+func (l *Logger) SetBurstSampler(burst int, thereafter int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if burst <= 0 {
+		l.burst = nil
+		return
+	}
+	if thereafter < 0 {
+		thereafter = 0
+	}
+	l.burst = newBurstSampler(uint64(burst), uint64(thereafter))
+}
+
+func SetBurstSampler(burst int, thereafter int) {
+	gStd.SetBurstSampler(burst, thereafter)
+}
+
+func newBurstSampler(burst uint64, thereafter uint64) *burstSampler {
+	return &burstSampler{burst: burst, thereafter: thereafter, counts: make(map[string]uint64)}
+}
+
+/*keep reports whether the next entry of msg is kept.*/
+func (s *burstSampler) keep(msg string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.counts[msg]
+	if !ok && len(s.counts) >= burstKeys {
+		s.counts = make(map[string]uint64)
+	}
+	n++
+	s.counts[msg] = n
+	if n <= s.burst {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.burst)%s.thereafter == 0
+}
+
+/*rateLimiter lets through rate entries per second, in bursts of up to rate, see SetRateLimit.*/
+type rateLimiter struct {
+	mu       sync.Mutex // protects tokens and last and orders the overflow writes, as the children of With share the limiter
+	rate     float64
+	tokens   float64
+	last     time.Time
+	overflow io.Writer
+}
+
+/*
+SetRateLimit writes at most perSecond entries per second, allowing bursts of
+up to perSecond entries after a quiet period. The entries over the rate are
+diverted to overflow, rendered as they would have been on the output, so that
+nothing is lost while the main stream stays readable; a nil overflow drops
+them. The overflow is written synchronously, even in async mode, and leaves
+out the handler, the line callbacks and the rotation. A perSecond of 0 or less
+removes the limit. Child loggers from With share the limit of l at the time of
+the call, so that their entries count towards the same rate.
+*/
+func (l *Logger) SetRateLimit(perSecond int, overflow io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if perSecond <= 0 {
+		l.rate = nil
+		return
+	}
+	l.rate = newRateLimiter(float64(perSecond), overflow)
+}
+
+func SetRateLimit(perSecond int, overflow io.Writer) {
+	gStd.SetRateLimit(perSecond, overflow)
+}
+
+func newRateLimiter(rate float64, overflow io.Writer) *rateLimiter {
+	return &rateLimiter{rate: rate, tokens: rate, overflow: overflow}
+}
+
+/*allow reports whether an entry logged at t is within the rate.*/
+func (r *rateLimiter) allow(t time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.last.IsZero() && t.After(r.last) {
+		if r.tokens += t.Sub(r.last).Seconds() * r.rate; r.tokens > r.rate {
+			r.tokens = r.rate
+		}
+	}
+	if t.After(r.last) {
+		r.last = t
+	}
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+/*writeOverflow renders e, followed by the bytes of p, to the overflow of the rate limiter, l.mu must be held.*/
+func (l *Logger) writeOverflow(e Entry, p []byte) error {
+	if l.rate.overflow == nil {
+		return nil
+	}
+	e.Message += string(p)
+	if l.formatter == nil {
+		l.buf = l.appendText(l.buf[:0], &e, nil)
+	} else {
+		l.buf = l.format(l.buf[:0], &e)
+	}
+	if l.lineSuffix != nil {
+		l.buf = l.appendSuffix(l.buf, e)
+	}
+	l.rate.mu.Lock()
+	defer l.rate.mu.Unlock()
+	_, err := l.rate.overflow.Write(l.buf)
+	return err
+}
@@ -0,0 +1,116 @@
+package glog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLevelSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetLevelSampling(map[int]int{INFO: 10, ERROR: 5})
+	for i := 0; i < 1000; i++ {
+		logger.Info("chatter")
+		if i%10 == 0 {
+			logger.Err("failure")
+		}
+	}
+	if n := strings.Count(buf.String(), "[INFO]: chatter"); n != 100 {
+		t.Errorf("%d info lines kept, want 100", n)
+	}
+	if n := strings.Count(buf.String(), "[ERROR]: failure"); n != 100 {
+		t.Errorf("%d error lines kept, want all 100", n)
+	}
+
+	buf.Reset()
+	logger.SetLevelSampling(nil)
+	logger.Info("chatter")
+	logger.Info("chatter")
+	if n := strings.Count(buf.String(), "chatter"); n != 2 {
+		t.Errorf("%d lines kept without sampling, want 2", n)
+	}
+}
+
+func TestSetBurstSampler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetBurstSampler(3, 10)
+	for i := 0; i < 43; i++ {
+		logger.Err("disk full")
+		logger.Info("request %d", i)
+	}
+	// 3 in full, then the 13th, 23rd, 33rd and 43rd
+	if n := strings.Count(buf.String(), "disk full"); n != 7 {
+		t.Errorf("%d repeated lines kept, want 7", n)
+	}
+	if n := strings.Count(buf.String(), "request"); n != 43 {
+		t.Errorf("%d distinct lines kept, want all 43", n)
+	}
+	first := strings.SplitN(buf.String(), "\n", 7)
+	if first[0] != "[ERROR]: disk full" || first[2] != "[ERROR]: disk full" || first[4] != "[ERROR]: disk full" || first[6] == "[ERROR]: disk full" {
+		t.Errorf("the burst did not come first: %q", buf.String())
+	}
+	if len(logger.burst.counts) > burstKeys {
+		t.Errorf("%d messages counted", len(logger.burst.counts))
+	}
+
+	// the children share the counts of their parent
+	buf.Reset()
+	logger.SetBurstSampler(2, 0)
+	for i := 0; i < 5; i++ {
+		logger.With("request", i).Warn("retrying")
+	}
+	if n := strings.Count(buf.String(), "retrying"); n != 2 {
+		t.Errorf("%d lines kept across the children, want 2", n)
+	}
+}
+
+func TestSetRateLimit(t *testing.T) {
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	var buf, overflow bytes.Buffer
+	logger := newEx(&buf, "", 0)
+	logger.SetRateLimit(10, &overflow)
+	for i := 0; i < 25; i++ {
+		logger.Info("flood")
+	}
+	clock = clock.Add(500 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		logger.Info("flood")
+	}
+	if main, over := strings.Count(buf.String(), "\n"), strings.Count(overflow.String(), "\n"); main != 15 || over != 20 {
+		t.Errorf("%d lines written and %d diverted, want 15 and 20", main, over)
+	}
+	if !strings.HasPrefix(overflow.String(), "[INFO]: flood\n") {
+		t.Errorf("overflow starts with %q", overflow.String())
+	}
+
+	// the children share the limit of their parent
+	buf.Reset()
+	overflow.Reset()
+	clock = clock.Add(time.Second)
+	for i := 0; i < 5; i++ {
+		logger.With("request", i).Info("flood")
+		logger.Info("flood")
+	}
+	if main, over := strings.Count(buf.String(), "\n"), strings.Count(overflow.String(), "\n"); main != 10 || over != 0 {
+		t.Errorf("with children %d lines written and %d diverted, want 10 and 0", main, over)
+	}
+	logger.With("request", 5).Info("flood")
+	if over := strings.Count(overflow.String(), "\n"); over != 1 {
+		t.Errorf("a child over the shared rate diverted %d lines, want 1", over)
+	}
+
+	logger.SetRateLimit(1, nil)
+	buf.Reset()
+	logger.Info("kept")
+	logger.Info("dropped")
+	logger.SetRateLimit(0, nil)
+	logger.Info("kept")
+	if buf.String() != "[INFO]: kept\n[INFO]: kept\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
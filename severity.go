@@ -0,0 +1,52 @@
+package glog
+
+import "sync"
+
+var (
+	severityMu      sync.RWMutex // protects severityMapping
+	severityMapping map[int]int
+)
+
+/*
+SetLevelMapping overrides the syslog severities the sinks send for the levels,
+GELF and journald alike, e.g. {DEBUG: 6} to report debug lines as informational.
+The levels missing from m keep their default severity, a nil m restores them all.
+*/
+func SetLevelMapping(m map[int]int) {
+	var mapping map[int]int
+	if len(m) > 0 {
+		mapping = make(map[int]int, len(m))
+		for level, severity := range m {
+			mapping[level] = severity
+		}
+	}
+	severityMu.Lock()
+	defer severityMu.Unlock()
+	severityMapping = mapping
+}
+
+/*
+SyslogSeverity returns the syslog severity of a level, as set with
+SetLevelMapping. By default DEBUG maps to debug (7), INFO and NOLEVEL to
+informational (6), WARNING to warning (4), ERROR to error (3) and FATAL to
+critical (2).
+*/
+func SyslogSeverity(level int) int {
+	severityMu.RLock()
+	severity, ok := severityMapping[level]
+	severityMu.RUnlock()
+	if ok {
+		return severity
+	}
+	switch level {
+	case DEBUG:
+		return 7
+	case WARNING:
+		return 4
+	case ERROR:
+		return 3
+	case FATAL:
+		return 2
+	}
+	return 6
+}
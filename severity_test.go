@@ -0,0 +1,32 @@
+package glog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetLevelMapping(t *testing.T) {
+	defer SetLevelMapping(nil)
+	h := &GELFHandler{Host: "test"}
+	level := func() float64 {
+		var record map[string]interface{}
+		if err := json.Unmarshal(h.appendPayload(nil, &Entry{Level: DEBUG, Message: "m"}), &record); err != nil {
+			t.Fatal(err)
+		}
+		return record["level"].(float64)
+	}
+	if got := level(); got != 7 {
+		t.Errorf("default DEBUG severity %v, want 7", got)
+	}
+	SetLevelMapping(map[int]int{DEBUG: 6})
+	if got := level(); got != 6 {
+		t.Errorf("mapped DEBUG severity %v, want 6", got)
+	}
+	if got := SyslogSeverity(ERROR); got != 3 {
+		t.Errorf("unmapped ERROR severity %d, want 3", got)
+	}
+	SetLevelMapping(nil)
+	if got := level(); got != 7 {
+		t.Errorf("restored DEBUG severity %v, want 7", got)
+	}
+}
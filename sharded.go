@@ -0,0 +1,87 @@
+package glog
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+/*
+A ShardedHandler routes entries to one of several rotating files by a key
+derived from the entry, e.g. a tenant ID, so that each key's lines stay apart.
+Each shard is a FileSink of its own, opened on first use, rotating
+independently. It is safe for concurrent use.
+*/
+type ShardedHandler struct {
+	Formatter  Formatter // renders the entries, a TextFormatter with LstdFlags when nil
+	SplitSize  int       // split size of each shard in MB, as in NewEx
+	SplitCount int       // total rotate split count of each shard, as in NewEx
+
+	mu      sync.Mutex
+	pattern string
+	keyFn   func(Entry) string
+	shards  []*WriterHandler
+	sinks   []*FileSink
+}
+
+/*
+NewSharded returns a Handler writing the entries to shards files named by
+formatting pattern with the shard number, e.g. "app-%d.log". An entry goes to
+the shard picked by hashing keyFn(entry), so all the entries of a key share a file.
+A pattern which does not format the number alone, such as one without %d, makes
+Handle fail rather than write the shards to garbled names.
+*/
+func NewSharded(pattern string, shards int, keyFn func(Entry) string) *ShardedHandler {
+	if shards < 1 {
+		shards = 1
+	}
+	return &ShardedHandler{SplitSize: SPLIT_FILE_SIZE, SplitCount: TOTAL_ROTATE_SPLIT, pattern: pattern, keyFn: keyFn,
+		shards: make([]*WriterHandler, shards), sinks: make([]*FileSink, shards)}
+}
+
+/*Shard returns the number of the shard receiving the entries of key.*/
+func (h *ShardedHandler) Shard(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(h.shards)))
+}
+
+/*Handle implements Handler.*/
+func (h *ShardedHandler) Handle(e *Entry) error {
+	i := h.Shard(h.keyFn(*e))
+	h.mu.Lock()
+	shard := h.shards[i]
+	if shard == nil {
+		path := fmt.Sprintf(h.pattern, i)
+		if strings.Contains(path, "%!") {
+			h.mu.Unlock()
+			return fmt.Errorf("glog: the shard pattern %q does not take the shard number as in app-%%d.log", h.pattern)
+		}
+		sink := NewFileSink(path, h.SplitSize, h.SplitCount)
+		if sink == nil {
+			h.mu.Unlock()
+			return fmt.Errorf("glog: cannot open the shard %s", path)
+		}
+		shard = NewWriterHandler(sink, h.Formatter)
+		h.shards[i] = shard
+		h.sinks[i] = sink
+	}
+	h.mu.Unlock()
+	return shard.Handle(e)
+}
+
+/*Close closes the files of the opened shards.*/
+func (h *ShardedHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var errs []error
+	for i, sink := range h.sinks {
+		if sink != nil {
+			errs = append(errs, sink.Close())
+			h.sinks[i], h.shards[i] = nil, nil
+		}
+	}
+	return errors.Join(errs...)
+}
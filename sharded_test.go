@@ -0,0 +1,57 @@
+package glog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharded(t *testing.T) {
+	pattern := filepath.Join(t.TempDir(), "app-%d.log")
+	tenant := func(e Entry) string {
+		for _, f := range e.Fields {
+			if f.Key == "tenant" {
+				return fmt.Sprint(f.Value)
+			}
+		}
+		return ""
+	}
+	h := NewSharded(pattern, 4, tenant)
+	h.Formatter = &TextFormatter{}
+	defer h.Close()
+	// pick two tenants landing on different shards
+	a, b := "acme", "globex"
+	for i := 0; h.Shard(a) == h.Shard(b); i++ {
+		b = fmt.Sprintf("globex-%d", i)
+	}
+
+	logger := newEx(nil, "", 0)
+	logger.SetHandler(h)
+	logger.With("tenant", a).Info("for a")
+	logger.With("tenant", b).Info("for b")
+	logger.With("tenant", a).Info("for a again")
+
+	fileA := fmt.Sprintf(pattern, h.Shard(a))
+	fileB := fmt.Sprintf(pattern, h.Shard(b))
+	if data, _ := os.ReadFile(fileA); string(data) != "[INFO]: for a tenant="+a+"\n[INFO]: for a again tenant="+a+"\n" {
+		t.Errorf("%s holds %q", fileA, data)
+	}
+	if data, _ := os.ReadFile(fileB); string(data) != "[INFO]: for b tenant="+b+"\n" {
+		t.Errorf("%s holds %q", fileB, data)
+	}
+}
+
+func TestShardedBadPattern(t *testing.T) {
+	dir := t.TempDir()
+	for _, pattern := range []string{"app.log", "app-%d-%d.log", "app-%s.log"} {
+		h := NewSharded(filepath.Join(dir, pattern), 2, func(Entry) string { return "" })
+		if err := h.Handle(&Entry{Level: INFO, Message: "lost"}); err == nil {
+			t.Errorf("pattern %q accepted", pattern)
+		}
+		h.Close()
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("created %v", entries)
+	}
+}
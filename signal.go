@@ -0,0 +1,38 @@
+package glog
+
+import (
+	"os"
+	"os/signal"
+)
+
+/*
+HandleRotateSignal rotates the log file every time the process receives sig,
+a nil sig means SIGUSR1 (not available on Windows, where nil installs nothing).
+It complements the size based rotation for operators driving rotation from
+outside, e.g. with "kill -USR1 <pid>". The returned function stops the handling.
+*/
+func (l *Logger) HandleRotateSignal(sig os.Signal) (stop func()) {
+	if sig == nil {
+		sig = defaultRotateSignal
+	}
+	if sig == nil {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				_ = l.Rotate()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
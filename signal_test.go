@@ -0,0 +1,40 @@
+//go:build !windows
+
+package glog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleRotateSignal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testF.log")
+	var logger = New(filename, "[Info] ", 0)
+	stop := logger.HandleRotateSignal(nil)
+	defer stop()
+	logger.Println("before rotation")
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, err := os.ReadFile(filename + ".0"); err == nil {
+			if string(data) != "[Info] before rotation\n" {
+				t.Errorf("archive holds %q", data)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no rotation after SIGUSR1")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	logger.Println("after rotation")
+	if data, _ := os.ReadFile(filename); string(data) != "[Info] after rotation\n" {
+		t.Errorf("active file holds %q", data)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package glog
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultRotateSignal os.Signal = syscall.SIGUSR1
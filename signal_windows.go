@@ -0,0 +1,7 @@
+//go:build windows
+
+package glog
+
+import "os"
+
+var defaultRotateSignal os.Signal // no SIGUSR1 on Windows
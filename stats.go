@@ -0,0 +1,31 @@
+package glog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*Stats holds the counters of a logger, see Logger.Stats.*/
+type Stats struct {
+	Rotations       uint64        // rotations of the log file so far
+	FailedRotations uint64        // rotations which could not archive the file or reopen it
+	LastRotation    time.Duration // how long the last rotation took, archiving the file and reopening it
+	LastArchive     time.Duration // how long the archive handler took on the last file it was done with
+	Dropped         uint64        // entries dropped by the current async queue
+}
+
+/*
+Stats returns the counters of the logger, e.g. for a metrics endpoint. A
+slow LastRotation points at the file system, a slow LastArchive at the
+archive handler, such as the compression of the files, which runs after the
+rotation in a goroutine of its own.
+*/
+func (l *Logger) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := Stats{Rotations: l.rotations, FailedRotations: l.rotateErrors, LastRotation: l.lastRotation, LastArchive: l.lastArchive}
+	if l.async != nil {
+		s.Dropped = atomic.LoadUint64(&l.async.dropped)
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package glog
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testT.log")
+	logger := NewExBytes(filename, "", 0, 16, 5)
+	logger.SetArchiveHandler(func(path string) error {
+		time.Sleep(10 * time.Millisecond) // a slow compression
+		return nil
+	})
+	if s := logger.Stats(); s != (Stats{}) {
+		t.Errorf("fresh logger has %+v", s)
+	}
+	logger.Println("more than sixteen bytes")
+	logger.Close()
+	s := logger.Stats()
+	if s.Rotations != 1 || s.LastRotation <= 0 || s.LastArchive < 10*time.Millisecond {
+		t.Errorf("got %+v, want one rotation with its durations", s)
+	}
+
+	// a rotation which cannot archive the file is not counted as one
+	filename = filepath.Join(t.TempDir(), "testT.log")
+	logger = NewExBytes(filename, "", 0, 16, 5)
+	logger.SetInternalErrorWriter(io.Discard)
+	rename = func(src, dst string) error {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: os.ErrPermission}
+	}
+	defer func() { rename = os.Rename }()
+	logger.Println("more than sixteen bytes")
+	logger.Close()
+	if s := logger.Stats(); s.Rotations != 0 || s.FailedRotations != 1 {
+		t.Errorf("got %+v after a failed rotation", s)
+	}
+}
@@ -0,0 +1,41 @@
+package glog
+
+import "context"
+
+/*TAIL_BUFFER is the number of lines a Tail stream holds for a slow reader.*/
+const TAIL_BUFFER = 1024
+
+/*
+Tail streams the lines written to the log file from now on, without their
+newline, like tail -f. It follows the logger across the rotations, as the
+lines come from the logger rather than the file. The stream holds up to
+TAIL_BUFFER lines for a slow reader, those written while it is full are
+dropped so that the logger never waits for the reader. The channel is
+closed once ctx is done. Tail returns ErrNotFile for a logger without a file.
+*/
+func (l *Logger) Tail(ctx context.Context) (<-chan string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.filename == "" {
+		return nil, ErrNotFile
+	}
+	lines := make(chan string, TAIL_BUFFER)
+	c := &lineCallback{fn: func(line []byte) {
+		if n := len(line); n > 0 && line[n-1] == '\n' {
+			line = line[:n-1]
+		}
+		select {
+		case lines <- string(line):
+		default:
+		}
+	}}
+	l.lineCallbacks = append(l.lineCallbacks, c)
+	go func() {
+		<-ctx.Done()
+		l.mu.Lock()
+		l.removeLineCallback(c)
+		l.mu.Unlock()
+		close(lines)
+	}()
+	return lines, nil
+}
@@ -0,0 +1,42 @@
+package glog
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestTail(t *testing.T) {
+	logger := NewEx(filepath.Join(t.TempDir(), "testO.log"), "", 0, 1, 5)
+	defer logger.Close()
+	logger.splitFileSize = 20
+	logger.Println("before")
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, err := logger.Tail(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		logger.Println("line " + strconv.Itoa(i))
+	}
+	for i := 0; i < 10; i++ {
+		if line := <-lines; line != "line "+strconv.Itoa(i) {
+			t.Errorf("line %d is %q", i, line)
+		}
+	}
+	if logger.splitRotateIndex == 0 {
+		t.Error("the lines did not span a rotation")
+	}
+	cancel()
+	for line := range lines {
+		t.Errorf("got %q after the cancellation", line)
+	}
+	logger.Println("after")
+	if len(logger.lineCallbacks) != 0 {
+		t.Error("the tail callback is still registered")
+	}
+	if _, err := newEx(nil, "", 0).Tail(ctx); err != ErrNotFile {
+		t.Errorf("Tail without a file returned %v", err)
+	}
+}
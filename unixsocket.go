@@ -0,0 +1,125 @@
+package glog
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+/*Reconnection delays of a UnixSocket.*/
+const (
+	UNIX_MIN_BACKOFF = 100 * time.Millisecond
+	UNIX_MAX_BACKOFF = 30 * time.Second
+)
+
+var errUnixBackoff = errors.New("glog: the unix socket is down, waiting to reconnect")
+
+/*
+A UnixSocket writes the lines of its loggers to a local agent listening on a
+unix domain socket, stream or datagram, one line per write. When the socket
+fails the line is retried once on a fresh connection; when the agent cannot
+be reached the lines are dropped, returning an error, and the sink only
+dials again after a delay growing from MinBackoff to MaxBackoff, so a dead
+agent does not cost a dial per line. A UnixSocket is safe for concurrent use.
+*/
+type UnixSocket struct {
+	MinBackoff time.Duration // delay after the first failed dial, UNIX_MIN_BACKOFF when zero
+	MaxBackoff time.Duration // longest delay between dials, UNIX_MAX_BACKOFF when zero
+
+	mu      sync.Mutex
+	path    string
+	network string // "unix" or "unixgram" once known
+	conn    net.Conn
+	backoff time.Duration // delay after the next failed dial
+	retry   time.Time     // no dial before
+}
+
+/*NewUnixSocket connects to the unix socket at path, a stream socket or else a datagram one.*/
+func NewUnixSocket(path string) (*UnixSocket, error) {
+	s := &UnixSocket{path: path}
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+/*NewLogger creates a Logger writing to the socket, with the rotation disabled.*/
+func (s *UnixSocket) NewLogger(prefix string, flag int) *Logger {
+	l := newEx(s, prefix, flag)
+	l.splitFileSize = 0
+	return l
+}
+
+/*Write sends one formatted line, reconnecting if need be.*/
+func (s *UnixSocket) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		n, err := s.conn.Write(p)
+		if err == nil {
+			return n, nil
+		}
+		// the agent may be back already, e.g. after a restart
+		s.conn.Close()
+		s.conn = nil
+	}
+	if time.Now().Before(s.retry) {
+		return 0, errUnixBackoff
+	}
+	if err := s.dial(); err != nil {
+		return 0, err
+	}
+	n, err := s.conn.Write(p)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return n, err
+}
+
+/*dial connects to the socket, or delays the next dial on failure, s.mu must be held.*/
+func (s *UnixSocket) dial() error {
+	var conn net.Conn
+	var err error
+	if s.network != "" {
+		conn, err = net.Dial(s.network, s.path)
+	} else if conn, err = net.Dial("unix", s.path); err == nil {
+		s.network = "unix"
+	} else if conn, err = net.Dial("unixgram", s.path); err == nil {
+		s.network = "unixgram"
+	}
+	if err != nil {
+		if s.backoff == 0 {
+			s.backoff = s.MinBackoff
+			if s.backoff <= 0 {
+				s.backoff = UNIX_MIN_BACKOFF
+			}
+		}
+		s.retry = time.Now().Add(s.backoff)
+		max := s.MaxBackoff
+		if max <= 0 {
+			max = UNIX_MAX_BACKOFF
+		}
+		if s.backoff *= 2; s.backoff > max {
+			s.backoff = max
+		}
+		return err
+	}
+	s.conn = conn
+	s.backoff = 0
+	s.retry = time.Time{}
+	return nil
+}
+
+/*Close closes the connection, the loggers writing to the socket must not be used afterwards.*/
+func (s *UnixSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
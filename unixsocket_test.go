@@ -0,0 +1,86 @@
+package glog
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*acceptLine accepts a connection on ln and returns the reader of its lines.*/
+func acceptLine(t *testing.T, ln net.Listener) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skip("no unix sockets:", err)
+	}
+	sink, err := NewUnixSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	sink.MinBackoff = 20 * time.Millisecond
+	logger := sink.NewLogger("", 0)
+	logger.Info("one")
+	conn, r := acceptLine(t, ln)
+	if line, err := r.ReadString('\n'); line != "[INFO]: one\n" {
+		t.Errorf("received %q, %v", line, err)
+	}
+
+	// the agent goes away
+	conn.Close()
+	ln.Close()
+	if err := logger.Output(1, "lost"); err == nil {
+		t.Error("write to a closed agent succeeded")
+	}
+	if err := logger.Output(1, "skipped"); err != errUnixBackoff {
+		t.Errorf("write within the backoff: %v, want errUnixBackoff", err)
+	}
+
+	// and comes back
+	ln, err = net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	time.Sleep(30 * time.Millisecond)
+	if err := logger.Output(1, "back"); err != nil {
+		t.Fatalf("write after the backoff: %v", err)
+	}
+	conn, r = acceptLine(t, ln)
+	defer conn.Close()
+	if line, err := r.ReadString('\n'); line != "back\n" {
+		t.Errorf("received %q, %v after reconnecting", line, err)
+	}
+}
+
+func TestUnixSocketDatagram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip("no unix datagram sockets:", err)
+	}
+	defer pc.Close()
+	sink, err := NewUnixSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	sink.NewLogger("", 0).Err("datagram")
+	buf := make([]byte, 256)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, _, err := pc.ReadFrom(buf); string(buf[:n]) != "[ERROR]: datagram\n" {
+		t.Errorf("received %q, %v", buf[:n], err)
+	}
+}